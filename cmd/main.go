@@ -1,15 +1,120 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/samaelod/nabu/config"
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/pcapwriter"
+	"github.com/samaelod/nabu/remap"
 	"github.com/samaelod/nabu/tui"
 )
 
 var version = "dev"
 
+// stringList collects a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ", ") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// paramList collects repeatable -p name=value scenario parameters.
+type paramList map[string]string
+
+func (p paramList) String() string {
+	var parts []string
+	for name, v := range p {
+		parts = append(parts, name+"="+v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p paramList) Set(v string) error {
+	name, value, err := lua.ParseParam(v)
+	if err != nil {
+		return err
+	}
+	p[name] = value
+	return nil
+}
+
+const paramUsage = "scenario parameter name=value, repeatable"
+
+// parseArgs parses flags placed anywhere among args, so both
+// "run -p a=1 scenario.lua" and "run scenario.lua -p a=1" work, and returns
+// the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var rest []string
+	for {
+		fs.Parse(args) // Exits on error
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+const remapUsage = "endpoint remap rule, repeatable (e.g. \"10.1.0.0/16 -> 127.0.0.1\")"
+
+// scenarioFlags registers -p and -remap on fs.
+func scenarioFlags(fs *flag.FlagSet) (paramList, *stringList) {
+	params := paramList{}
+	remaps := &stringList{}
+	fs.Var(params, "p", paramUsage)
+	fs.Var(remaps, "remap", remapUsage)
+	return params, remaps
+}
+
+// scriptOptions returns the Lua options of the app config with params, and
+// with the command-line remap rules tried before those of nabu.json.
+func scriptOptions(params paramList, remaps stringList) lua.Options {
+	appConfig, err := config.LoadDefault()
+	if err != nil {
+		appConfig = config.Default()
+	}
+	opts := lua.OptionsFor(appConfig)
+	opts.Params = params
+	opts.Remap = slices.Concat(remaps, opts.Remap)
+	return opts
+}
+
 func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "export":
+			os.Exit(export(args[1:]))
+		case "lint":
+			os.Exit(lint(args[1:]))
+		}
+	}
+
+	// "nabu run <scenario>" opens the scenario directly
+	runScenario := len(args) > 0 && args[0] == "run"
+	if runScenario {
+		args = args[1:]
+	}
+
+	params, remaps := scenarioFlags(flag.CommandLine)
+	rest := parseArgs(flag.CommandLine, args)
+
+	var scenario string
+	switch {
+	case runScenario && len(rest) == 1:
+		scenario = rest[0]
+	case runScenario || len(rest) > 0:
+		fmt.Fprintln(os.Stderr, "usage: nabu [run <scenario.lua|capture.pcap>] [-p name=value]... [-remap rule]...")
+		os.Exit(2)
+	}
+
 	// Only create debug log in dev builds
 	if version == "dev" {
 		f, err := os.OpenFile("debug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
@@ -18,7 +123,70 @@ func main() {
 		}
 	}
 
-	if err := tui.Run(version); err != nil {
+	if _, err := remap.ParseAll(*remaps); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := tui.Run(version, tui.Options{Scenario: scenario, Params: params, Remap: *remaps}); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// export converts a Lua scenario into a synthetic capture without running it.
+func export(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	params, remaps := scenarioFlags(fs)
+	args = parseArgs(fs, args)
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: nabu export <scenario.lua> <out.pcap|out.pcapng> [-p name=value]... [-remap rule]...")
+		return 2
+	}
+
+	cfg, err := lua.LoadScenario(context.Background(), args[0], scriptOptions(params, *remaps))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := pcapwriter.SaveConfig(args[1], cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// lint prints every problem in the given scenarios, remapped as they would
+// be loaded, and fails if any of them would be rejected.
+func lint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	params, remaps := scenarioFlags(fs)
+	paths := parseArgs(fs, args)
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: nabu lint <scenario.lua|capture.pcap>... [-p name=value]... [-remap rule]...")
+		return 2
+	}
+
+	opts := scriptOptions(params, *remaps)
+	status := 0
+	for _, path := range paths {
+		problems, err := lua.LintFileWithOptions(context.Background(), path, opts)
+		if err != nil {
+			// Script errors already start with the file they come from
+			if msg := err.Error(); strings.HasPrefix(msg, path) {
+				fmt.Println(msg)
+			} else {
+				fmt.Printf("%s: %s\n", path, msg)
+			}
+			status = 1
+			continue
+		}
+
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", path, p)
+			if p.Severity == lua.SeverityError {
+				status = 1
+			}
+		}
+	}
+	return status
+}
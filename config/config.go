@@ -2,19 +2,32 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 )
 
 type Config struct {
-	LogLines  int    `json:"log_lines"`
-	LogsDir   string `json:"logs_dir"`
-	RecentDir string `json:"recent_dir"`
+	LogLines       int      `json:"log_lines"`
+	LogsDir        string   `json:"logs_dir"`
+	RecentDir      string   `json:"recent_dir"`
+	Remap          []string `json:"remap"`             // Endpoint remap rules applied to every scenario
+	LogLevel       string   `json:"log_level"`         // debug, info, warn or error
+	LogFormat      string   `json:"log_format"`        // text or json
+	LogMaxSizeMB   int      `json:"log_max_size_mb"`   // Rotate the log file past this size (0 = never)
+	LogMaxAgeHours int      `json:"log_max_age_hours"` // Rotate and prune logs older than this (0 = never)
+	LogMaxBackups  int      `json:"log_max_backups"`   // Rotated files kept per scenario (0 = all)
+	Verbose        bool     `json:"verbose"`           // Hex dumps in the log and per-connection transcripts
+	Capture        string   `json:"capture"`           // Write each run to logs_dir as "pcap" or "pcapng" (empty = off)
+	LuaSandbox     bool     `json:"lua_sandbox"`       // Run scenarios with only the safe Lua libraries
+	LuaTimeoutMs   int      `json:"lua_timeout_ms"`    // Abort scenario scripts running longer (0 = no limit)
+	LuaMemoryMB    int      `json:"lua_memory_mb"`     // Abort scenario scripts allocating more (0 = no limit)
 }
 
 var (
 	defaultConfig *Config
+	defaultErr    error // Why the cached config failed to load
 	once          sync.Once
 )
 
@@ -23,6 +36,8 @@ func Default() *Config {
 		LogLines:  1000,
 		LogsDir:   "logs",
 		RecentDir: "recent",
+		LogLevel:  "info",
+		LogFormat: "text",
 	}
 }
 
@@ -71,18 +86,46 @@ func Load(path string) (*Config, error) {
 	if cfg.RecentDir == "" {
 		cfg.RecentDir = "recent"
 	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "text"
+	}
+
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return nil, fmt.Errorf("%s: unknown log_level %q (want debug, info, warn or error)", path, cfg.LogLevel)
+	}
+	switch cfg.LogFormat {
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("%s: unknown log_format %q (want text or json)", path, cfg.LogFormat)
+	}
+	switch cfg.Capture {
+	case "", "pcap", "pcapng":
+	default:
+		return nil, fmt.Errorf("%s: unknown capture format %q (want pcap or pcapng)", path, cfg.Capture)
+	}
+	if cfg.LogMaxSizeMB < 0 || cfg.LogMaxAgeHours < 0 || cfg.LogMaxBackups < 0 {
+		return nil, fmt.Errorf("%s: log rotation limits must not be negative", path)
+	}
+	if cfg.LuaTimeoutMs < 0 || cfg.LuaMemoryMB < 0 {
+		return nil, fmt.Errorf("%s: lua limits must not be negative", path)
+	}
 
 	return cfg, nil
 }
 
-// LoadDefault loads the config once and caches it
+// LoadDefault loads the config once and caches it. When loading failed,
+// every call returns the defaults with the load error.
 func LoadDefault() (*Config, error) {
-	var err error
 	once.Do(func() {
-		defaultConfig, err = Load("")
+		defaultConfig, defaultErr = Load("")
 	})
-	if err != nil {
-		return Default(), err
+	if defaultErr != nil {
+		return Default(), defaultErr
 	}
 	return defaultConfig, nil
 }
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samaelod/nabu/pcapwriter"
+)
+
+// capture writes the traffic of a run to a pcap file. When both ends of a
+// connection are emulated by this engine, only the sending side records
+// data so every segment appears once.
+type capture struct {
+	w *pcapwriter.Writer
+
+	mu     sync.Mutex
+	locals map[string]*tuple // Connections with an end open, by address pair
+}
+
+// tuple counts the emulated ends of a connection. Once both ends have
+// opened it stays shared until the last one is released, so data in flight
+// after one end closed is not recorded twice.
+type tuple struct {
+	ends   int
+	shared bool
+}
+
+// connCapture is the capture state of one tracked connection.
+type connCapture struct {
+	c             *capture
+	key           string
+	t             *tuple
+	local, remote pcapwriter.Addr
+	mu            sync.Mutex
+	closed        bool // This side sent FIN or RST
+	released      bool
+}
+
+// EnableCapture records every connection's traffic to path (.pcap or
+// .pcapng). It must be called before any endpoint is started.
+func (e *Engine) EnableCapture(path string) error {
+	w, err := pcapwriter.Create(path)
+	if err != nil {
+		e.warn(fmt.Sprintf("Capture disabled: %v", err))
+		return err
+	}
+	e.capture = &capture{w: w, locals: make(map[string]*tuple)}
+	e.log(fmt.Sprintf("Capturing traffic to %s", path))
+	return nil
+}
+
+// open registers a connection seen from local; the first end to register
+// writes the handshake. Address pairs reused once all their ends were
+// released start a new connection.
+func (c *capture) open(local, remote pcapwriter.Addr, dialed bool) *connCapture {
+	key := local.String() + "|" + remote.String()
+	if remote.String() < local.String() {
+		key = remote.String() + "|" + local.String()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.locals[key]
+	if !ok {
+		t = &tuple{}
+		c.locals[key] = t
+
+		// Under c.mu, so the other end cannot record data before the
+		// handshake is written
+		client, server := remote, local
+		if dialed {
+			client, server = local, remote
+		}
+		c.w.Handshake(time.Now(), client, server)
+	}
+	t.ends++
+	t.shared = t.shared || t.ends > 1
+	return &connCapture{c: c, key: key, t: t, local: local, remote: remote}
+}
+
+// release unregisters this end once its connection is closed; the last end
+// drops the connection and its sequence numbers.
+func (cc *connCapture) release() {
+	if cc == nil {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.released {
+		return
+	}
+	cc.released = true
+
+	cc.c.mu.Lock()
+	defer cc.c.mu.Unlock()
+	cc.t.ends--
+	if cc.t.ends == 0 {
+		// Under c.mu, so a new connection between the same addresses
+		// cannot start before the old one is forgotten
+		delete(cc.c.locals, cc.key)
+		cc.c.w.Forget(cc.local, cc.remote)
+	}
+}
+
+// shared reports whether both ends of the connection are emulated here.
+func (cc *connCapture) shared() bool {
+	cc.c.mu.Lock()
+	defer cc.c.mu.Unlock()
+	return cc.t.shared
+}
+
+func (cc *connCapture) sent(b []byte) {
+	if cc == nil {
+		return
+	}
+	cc.c.w.Data(time.Now(), cc.local, cc.remote, b)
+}
+
+func (cc *connCapture) received(b []byte) {
+	if cc == nil || cc.shared() {
+		return
+	}
+	cc.c.w.Data(time.Now(), cc.remote, cc.local, b)
+}
+
+// peerClosed records the FIN of a peer that is not emulated here.
+func (cc *connCapture) peerClosed() {
+	if cc == nil || cc.shared() {
+		return
+	}
+	cc.c.w.Fin(time.Now(), cc.remote, cc.local)
+}
+
+// fin records this side's FIN once; rst records a reset instead.
+func (cc *connCapture) fin(rst bool) {
+	if cc == nil {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.closed {
+		return
+	}
+	cc.closed = true
+	if rst {
+		cc.c.w.Reset(time.Now(), cc.local, cc.remote)
+	} else {
+		cc.c.w.Fin(time.Now(), cc.local, cc.remote)
+	}
+}
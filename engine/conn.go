@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/samaelod/nabu/pcapwriter"
+	"github.com/samaelod/nabu/types"
+)
+
+// pollInterval bounds how long a read blocks before re-checking
+// cancellation and timeouts.
+const pollInterval = 100 * time.Millisecond
+
+// trackedConn records the last time data moved in either direction so idle
+// timeouts account for both reads and writes.
+type trackedConn struct {
+	net.Conn
+	lastActive atomic.Int64  // unix nanoseconds
+	lastRecv   atomic.Int64  // unix nanoseconds
+	unanswered atomic.Int64  // unix nanoseconds of the first write not yet answered, 0 if none
+	closed     atomic.Bool   // set by the first Close
+	done       chan struct{} // closed when the receive loop exits
+	transcript *transcript   // nil unless transcripts are enabled
+	capture    *connCapture  // nil unless capture is enabled
+	stats      *runStats     // nil when not counted
+}
+
+func newTrackedConn(c net.Conn) *trackedConn {
+	tc := &trackedConn{Conn: c, done: make(chan struct{})}
+	now := time.Now().UnixNano()
+	tc.lastActive.Store(now)
+	tc.lastRecv.Store(now)
+	return tc
+}
+
+// track wraps c for endpoint id talking to peer (-1 for accepted
+// connections) and attaches its capture and transcript when enabled.
+func (e *Engine) track(c net.Conn, id, peer int) *trackedConn {
+	tc := newTrackedConn(c)
+	tc.stats = &e.stats
+	e.stats.conns.Add(1)
+	if e.capture != nil {
+		tc.capture = e.capture.open(pcapwriter.AddrOf(c.LocalAddr()), pcapwriter.AddrOf(c.RemoteAddr()), peer >= 0)
+	}
+	if e.transcriptDir == "" {
+		return tc
+	}
+
+	t, err := e.openTranscript(c, id, peer)
+	if err != nil {
+		e.warnFor(id, fmt.Sprintf("Transcript for endpoint %d disabled: %v", id, err))
+		return tc
+	}
+	tc.transcript = t
+	return tc
+}
+
+func (c *trackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		now := time.Now().UnixNano()
+		c.lastActive.Store(now)
+		c.unanswered.CompareAndSwap(0, now)
+		c.transcript.record(true, b[:n])
+		c.capture.sent(b[:n])
+	}
+	return n, err
+}
+
+func (c *trackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		now := time.Now().UnixNano()
+		c.lastActive.Store(now)
+		c.lastRecv.Store(now)
+		if sent := c.unanswered.Swap(0); sent != 0 {
+			c.stats.latency(time.Duration(now - sent))
+		}
+		c.transcript.record(false, b[:n])
+		c.capture.received(b[:n])
+	}
+	return n, err
+}
+
+func (c *trackedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) && c.stats != nil {
+		c.stats.conns.Add(-1)
+	}
+	c.capture.fin(false)
+	c.capture.release()
+	c.transcript.close()
+	return c.Conn.Close()
+}
+
+func since(ts int64) time.Duration {
+	return time.Since(time.Unix(0, ts))
+}
+
+// receive drains data arriving on conn until the peer closes it, the context
+// is cancelled, or the read or idle timeout fires. The connection is closed
+// on return.
+func (e *Engine) receive(ctx context.Context, id, peer int, conn *trackedConn, t timeouts) {
+	defer close(conn.done)
+	defer conn.Close()
+
+	buf := make([]byte, 4096)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		conn.SetReadDeadline(time.Now().Add(pollInterval))
+		n, err := conn.Read(buf)
+		if n > 0 {
+			payload := append([]byte(nil), buf[:n]...)
+			e.emit(Event{Type: EventReceived, Endpoint: id, Peer: peer, Bytes: n, Payload: payload,
+				Text: fmt.Sprintf("Received %d bytes on endpoint %d", n, id)})
+		}
+		if err == nil {
+			continue
+		}
+		if !isTimeout(err) {
+			if errors.Is(err, io.EOF) {
+				conn.capture.peerClosed()
+			}
+			if ctx.Err() == nil {
+				e.emit(Event{Type: EventClosed, Endpoint: id, Peer: peer,
+					Text: fmt.Sprintf("Connection on endpoint %d closed: %v", id, err)})
+			}
+			return
+		}
+
+		if t.read > 0 && since(conn.lastRecv.Load()) >= t.read {
+			terr := &TimeoutError{Op: "read", Endpoint: id, Peer: peer, Timeout: t.read, Err: err}
+			e.emit(Event{Type: EventError, Endpoint: id, Peer: peer, Err: terr,
+				Text: fmt.Sprintf("Endpoint %d error: %v", id, terr)})
+			e.setStatus(id, types.StatusError)
+			return
+		}
+		if t.idle > 0 && since(conn.lastActive.Load()) >= t.idle {
+			terr := &TimeoutError{Op: "idle", Endpoint: id, Peer: peer, Timeout: t.idle, Err: err}
+			e.emit(Event{Type: EventClosed, Level: LevelWarn, Endpoint: id, Peer: peer, Err: terr,
+				Text: fmt.Sprintf("Endpoint %d closing connection: %v", id, terr)})
+			return
+		}
+	}
+}
+
+// closeWrite shuts down the sending side of conn (TCP half-close).
+func closeWrite(conn net.Conn) error {
+	if tc, ok := conn.(*trackedConn); ok {
+		tc.transcript.note("half-closed (fin sent)")
+		tc.capture.fin(false)
+		conn = tc.Conn
+	}
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return conn.Close()
+}
+
+// abort closes conn with SO_LINGER 0 so the peer receives a RST.
+func abort(conn net.Conn) error {
+	if tc, ok := conn.(*trackedConn); ok {
+		tc.transcript.note("reset (rst sent)")
+		tc.capture.fin(true)
+		tc.capture.release()
+		tc.transcript.close()
+		conn = tc.Conn
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		if err := tcp.SetLinger(0); err != nil {
+			tcp.Close()
+			return err
+		}
+	}
+	return conn.Close()
+}
+
+func (e *Engine) setStatus(id int, st types.EndpointStatus) {
+	e.Mutex.Lock()
+	changed := e.Status[id] != st
+	e.Status[id] = st
+	e.Mutex.Unlock()
+
+	if changed {
+		e.statusChanged(id, st)
+	}
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// sourcePool returns the local addresses a client endpoint should rotate
+// through. Endpoint-level pools take precedence over the global pool.
+func (e *Engine) sourcePool(ep *types.Endpoint) []string {
+	if ep != nil && len(ep.SourcePool) > 0 {
+		return ep.SourcePool
+	}
+	return e.scenario().Globals.SourcePool
+}
+
+// localAddr picks the local address a connection from ep should bind to.
+// Returns nil when the kernel should choose the source address.
+func (e *Engine) localAddr(ep *types.Endpoint) (*net.TCPAddr, error) {
+	if ep == nil {
+		return nil, nil
+	}
+
+	bind := ep.Bind || e.scenario().Globals.BindSource
+	port := 0
+	if bind {
+		port = ep.Port
+	}
+
+	host := ""
+	if pool := e.sourcePool(ep); len(pool) > 0 {
+		// Each endpoint walks the pool on its own, whatever the others do
+		e.Mutex.Lock()
+		host = pool[e.poolNext[ep.ID]%len(pool)]
+		e.poolNext[ep.ID]++
+		e.Mutex.Unlock()
+	} else if bind {
+		host = ep.Address
+	} else {
+		return nil, nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source address %q", host)
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// newDialer builds a dialer for connections originating from ep, binding the
+// local side when configured.
+func (e *Engine) newDialer(ep *types.Endpoint, timeout time.Duration) (*net.Dialer, error) {
+	d := &net.Dialer{Timeout: timeout}
+
+	local, err := e.localAddr(ep)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		d.LocalAddr = local
+		d.Control = func(network, address string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				serr = setReuseAddr(fd)
+			}); err != nil {
+				return err
+			}
+			return serr
+		}
+	}
+
+	return d, nil
+}
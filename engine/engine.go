@@ -3,10 +3,13 @@ package engine
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/samaelod/nabu/types"
@@ -14,13 +17,14 @@ import (
 
 // Engine handles the simulation/replay of network events from a Config.
 type Engine struct {
-	Config    *types.Config
+	Config    *types.Config // Read through scenario(), replaced by Reload
 	Running   bool
 	Stopped   bool
 	Listeners map[int]net.Listener     // Map of Server IDs to listeners
 	Clients   map[int]map[int]net.Conn // Map of [From -> [To -> Conn]]
 	Mutex     sync.Mutex
 	Log       *Logger
+	Events    *Bus         // Typed engine events, see Subscribe
 	ActiveEnd map[int]bool // Track which endpoints are running
 	Status    map[int]types.EndpointStatus
 	Ctx       context.Context    // Context for cancellation
@@ -28,6 +32,17 @@ type Engine struct {
 
 	activeCount   int                 // Number of active endpoints
 	endpointMutex map[int]*sync.Mutex // Per-endpoint mutexes for connection ops
+	poolNext      map[int]int         // Per-endpoint round-robin index into its source pool
+	metrics       map[int]*EndpointMetrics
+	runDone       map[int]chan struct{} // Closed when an endpoint's run goroutine returns
+	runStop       map[int]chan struct{} // Closed by StopEndpoint to cut the waits of a run short
+	load          LoadStats             // Load scheduler statistics
+	loadCancel    context.CancelFunc    // Non-nil while a load profile runs
+	transcriptDir string                // Per-connection transcripts, empty when off
+	transcriptSeq atomic.Int64
+	capture       *capture // Non-nil while a pcap of the run is written
+	stats         runStats
+	cfgMu         sync.RWMutex
 
 	timeout time.Duration // Connection timeout
 	delay   time.Duration // Default delay between messages
@@ -35,11 +50,16 @@ type Engine struct {
 
 // NewEngine creates a new simulation engine instance.
 func NewEngine(cfg *types.Config, logPath string, logLines int, timeoutMs int, delayMs int) *Engine {
-	ctx, cancel := context.WithCancel(context.Background())
-
 	if logLines <= 0 {
 		logLines = 1000
 	}
+	return NewEngineWithLogger(cfg, NewLogger(logPath, logLines), timeoutMs, delayMs)
+}
+
+// NewEngineWithLogger creates an engine that writes to an existing logger.
+func NewEngineWithLogger(cfg *types.Config, log *Logger, timeoutMs int, delayMs int) *Engine {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	if timeoutMs <= 0 {
 		timeoutMs = 5000
 	}
@@ -47,17 +67,27 @@ func NewEngine(cfg *types.Config, logPath string, logLines int, timeoutMs int, d
 		delayMs = 0
 	}
 
+	// Index messages for O(1) lookup by sender
+	if cfg.MessagesByFrom == nil {
+		cfg.IndexMessages()
+	}
+
 	return &Engine{
 		Config:        cfg,
 		Listeners:     make(map[int]net.Listener),
 		Clients:       make(map[int]map[int]net.Conn),
-		Log:           NewLogger(logPath, logLines),
+		Log:           log,
+		Events:        NewBus(),
 		ActiveEnd:     make(map[int]bool),
 		Status:        make(map[int]types.EndpointStatus),
 		Ctx:           ctx,
 		Cancel:        cancel,
 		activeCount:   0,
 		endpointMutex: make(map[int]*sync.Mutex),
+		poolNext:      make(map[int]int),
+		metrics:       make(map[int]*EndpointMetrics),
+		runDone:       make(map[int]chan struct{}),
+		runStop:       make(map[int]chan struct{}),
 		timeout:       time.Duration(timeoutMs) * time.Millisecond,
 		delay:         time.Duration(delayMs) * time.Millisecond,
 	}
@@ -66,9 +96,9 @@ func NewEngine(cfg *types.Config, logPath string, logLines int, timeoutMs int, d
 // StartEndpoint starts the simulation for a single endpoint.
 func (e *Engine) StartEndpoint(id int) {
 	e.Mutex.Lock()
-	defer e.Mutex.Unlock()
 
 	if e.ActiveEnd[id] {
+		e.Mutex.Unlock()
 		return
 	}
 
@@ -89,36 +119,66 @@ func (e *Engine) StartEndpoint(id int) {
 	}
 
 	e.Status[id] = types.StatusRunning
+	done, stop := make(chan struct{}), make(chan struct{})
+	e.runDone[id] = done
+	e.runStop[id] = stop
+	ctx := e.Ctx
+	e.Mutex.Unlock()
 
-	go e.runEndpoint(id, isServer)
+	e.statusChanged(id, types.StatusRunning)
+	go func() {
+		defer close(done)
+		e.runEndpoint(ctx, id, isServer, stop)
+	}()
+}
+
+// RestartEndpoint stops id and starts it again once its previous run has
+// wound down, so the two runs never share the endpoint's connections.
+// Endpoints that are not running are just started.
+func (e *Engine) RestartEndpoint(id int) {
+	e.Mutex.Lock()
+	done := e.runDone[id]
+	e.Mutex.Unlock()
+
+	e.StopEndpoint(id)
+	go func() {
+		if done != nil {
+			<-done
+		}
+		e.StartEndpoint(id)
+	}()
 }
 
 func (e *Engine) findEndpoint(id int) *types.Endpoint {
-	for i := range e.Config.Endpoints {
-		if e.Config.Endpoints[i].ID == id {
-			return &e.Config.Endpoints[i]
+	cfg := e.scenario()
+	for i := range cfg.Endpoints {
+		if cfg.Endpoints[i].ID == id {
+			return &cfg.Endpoints[i]
 		}
 	}
 	return nil
 }
 
-func (e *Engine) runEndpoint(id int, isServer bool) {
-	// Ensure messages are indexed
-	if e.Config.MessagesByFrom == nil {
-		e.Config.IndexMessages()
-	}
+// scenario returns the current scenario, which Reload may replace while
+// endpoints run.
+func (e *Engine) scenario() *types.Config {
+	e.cfgMu.RLock()
+	defer e.cfgMu.RUnlock()
+	return e.Config
+}
 
+func (e *Engine) runEndpoint(ctx context.Context, id int, isServer bool, stop <-chan struct{}) {
 	// Find endpoint config
 	ep := e.findEndpoint(id)
 	if ep == nil {
-		e.log(fmt.Sprintf("Endpoint %d not found", id))
+		e.warnFor(id, fmt.Sprintf("Endpoint %d not found", id))
 		if !isServer {
-			e.finishEndpoint(id)
+			e.finishEndpoint(id, stop)
 		}
 		return
 	}
 
-	e.log(fmt.Sprintf("Starting endpoint %d (%s)...", id, ep.Kind))
+	e.logFor(id, fmt.Sprintf("Starting endpoint %d (%s)...", id, ep.Kind))
 
 	if ep.Kind == "server" {
 		// Just start listener if not already
@@ -128,17 +188,14 @@ func (e *Engine) runEndpoint(id int, isServer bool) {
 
 		if !exists {
 			if err := e.setupListener(*ep); err != nil {
-				e.log(fmt.Sprintf("Error starting listener %d: %v", id, err))
-				e.Mutex.Lock()
-				e.Status[id] = types.StatusError
-				e.Mutex.Unlock()
+				e.emit(Event{Type: EventError, Endpoint: id, Peer: -1, Err: err,
+					Text: fmt.Sprintf("Error starting listener %d: %v", id, err)})
+				e.setStatus(id, types.StatusError)
 			} else {
-				e.Mutex.Lock()
-				e.Status[id] = types.StatusRunning // Listeners stay running
-				e.Mutex.Unlock()
+				e.setStatus(id, types.StatusRunning) // Listeners stay running
 			}
 		} else {
-			e.log(fmt.Sprintf("Listener %d already active", id))
+			e.logFor(id, fmt.Sprintf("Listener %d already active", id))
 		}
 		// Server creates no traffic on its own in this model (passive)
 		// Unless configured to send? Current setupListeners handles accept loop.
@@ -146,24 +203,80 @@ func (e *Engine) runEndpoint(id int, isServer bool) {
 	}
 
 	// Client Logic
+	// Without messages every iteration would end at once, flooding the log
+	// until the loop ends
+	if len(e.scenario().MessagesByFrom[id]) == 0 {
+		e.warnFor(id, fmt.Sprintf("Endpoint %d has no messages to send", id))
+		e.finishEndpoint(id, stop)
+		return
+	}
+
+	// Repeat the trace according to the endpoint's loop settings
+	loop := e.loopFor(ep)
+	runStart := time.Now()
+
+	for iter := 1; ; iter++ {
+		stats, ok := e.runIteration(ctx, id, iter, stop)
+		e.recordIteration(id, stats)
+		e.logFor(id, fmt.Sprintf("Endpoint %d iteration %d done in %v (%d msgs, %d errors, %d bytes)",
+			id, iter, stats.Duration.Round(time.Millisecond), stats.Messages, stats.Errors, stats.BytesSent))
+		// A stop has already wound the run down
+		if !ok || !e.IsRunning(id) {
+			return
+		}
+
+		if loop.done(iter, time.Since(runStart)) {
+			break
+		}
+
+		// Drop leftover connections so the next pass reconnects cleanly
+		e.endpointSession(id).closeAll()
+
+		if loop.think > 0 {
+			select {
+			case <-time.After(loop.think):
+			case <-stop:
+				return
+			case <-ctx.Done():
+				e.stoppedByUser(id, stop)
+				return
+			}
+		}
+	}
+
+	e.logFor(id, fmt.Sprintf("Endpoint %d finished trace.", id))
+	e.finishEndpoint(id, stop)
+}
+
+// runIteration plays the endpoint's messages once. It returns false when the
+// run was cancelled and the endpoint has already been finished.
+func (e *Engine) runIteration(ctx context.Context, id, iter int, stop <-chan struct{}) (IterationStats, bool) {
+	stats := IterationStats{Iteration: iter, Start: time.Now()}
+	ok := e.playTrace(ctx, e.endpointSession(id), &stats, stop, func() bool { return e.IsRunning(id) })
+	if !ok {
+		e.stoppedByUser(id, stop)
+	}
+	return stats, ok
+}
+
+// playTrace sends the messages of s.id once, honouring each TDelta. It stops
+// early when alive reports false or stop is closed, and returns false if ctx
+// was cancelled.
+func (e *Engine) playTrace(ctx context.Context, s *session, stats *IterationStats, stop <-chan struct{}, alive func() bool) bool {
 	// Iterate through messages where From == id using indexed map
 	startTime := time.Now()
+	defer func() { stats.Duration = time.Since(startTime) }()
 
-	messages := e.Config.MessagesByFrom[id]
+	messages := e.scenario().MessagesByFrom[s.id]
 	for i, msg := range messages {
 		// Check if context was cancelled
 		select {
-		case <-e.Ctx.Done():
-			e.log(fmt.Sprintf("Endpoint %d stopped by user", id))
-			e.Mutex.Lock()
-			e.Status[id] = types.StatusIdle
-			e.Mutex.Unlock()
-			e.finishEndpoint(id)
-			return
+		case <-ctx.Done():
+			return false
 		default:
 		}
 
-		if !e.IsRunning(id) {
+		if !alive() {
 			break
 		}
 
@@ -178,13 +291,10 @@ func (e *Engine) runEndpoint(id int, isServer bool) {
 		if waitDuration > 0 {
 			select {
 			case <-time.After(waitDuration):
-			case <-e.Ctx.Done():
-				e.log(fmt.Sprintf("Endpoint %d stopped by user", id))
-				e.Mutex.Lock()
-				e.Status[id] = types.StatusIdle
-				e.Mutex.Unlock()
-				e.finishEndpoint(id)
-				return
+			case <-stop:
+				return true
+			case <-ctx.Done():
+				return false
 			}
 		}
 
@@ -192,23 +302,39 @@ func (e *Engine) runEndpoint(id int, isServer bool) {
 		elapsed := time.Since(startTime).Milliseconds()
 
 		// Execute Action
-		err := e.executeMessage(msg)
+		e.emit(Event{Type: EventMessage, Endpoint: s.id, Peer: msg.To, Message: i})
+		stats.Messages++
+		err := e.executeMessage(ctx, s, msg, stats, stop)
 		if err != nil {
-			e.log(fmt.Sprintf("[+%dms] Error msg %d: %v", elapsed, i, err))
-			e.Mutex.Lock()
-			e.Status[id] = types.StatusError
-			e.Mutex.Unlock()
+			stats.Errors++
+			e.emit(Event{Type: EventError, Endpoint: s.id, Peer: msg.To, Err: err,
+				Text: fmt.Sprintf("[+%dms] Error msg %d: %v", elapsed, i, err)})
+			if s.errors {
+				e.setStatus(s.id, types.StatusError)
+			}
 		}
 	}
 
-	e.log(fmt.Sprintf("Endpoint %d finished trace.", id))
-	e.finishEndpoint(id)
+	return true
+}
+
+func (e *Engine) stoppedByUser(id int, stop <-chan struct{}) {
+	e.logFor(id, fmt.Sprintf("Endpoint %d stopped by user", id))
+	e.setStatus(id, types.StatusIdle)
+	e.finishEndpoint(id, stop)
 }
 
-func (e *Engine) finishEndpoint(id int) {
+// finishEndpoint marks the run identified by stop as completed. Runs that
+// StopEndpoint or StopAll already wound down are left alone, so they are
+// neither counted twice nor reported as completed.
+func (e *Engine) finishEndpoint(id int, stop <-chan struct{}) {
 	e.Mutex.Lock()
-	defer e.Mutex.Unlock()
 
+	if cur, ok := e.runStop[id]; !ok || cur != stop {
+		e.Mutex.Unlock()
+		return
+	}
+	delete(e.runStop, id)
 	delete(e.ActiveEnd, id)
 	e.activeCount--
 	if e.activeCount < 0 {
@@ -218,11 +344,15 @@ func (e *Engine) finishEndpoint(id int) {
 	if e.Status[id] != types.StatusError {
 		e.Status[id] = types.StatusCompleted
 	}
+	st := e.Status[id]
 
 	// Only set Running to false if no more endpoints are active
 	if e.activeCount == 0 {
 		e.Running = false
 	}
+	e.Mutex.Unlock()
+
+	e.statusChanged(id, st)
 }
 
 // GetStatus returns the current status of an endpoint safely
@@ -241,9 +371,9 @@ func (e *Engine) IsRunning(id int) bool {
 // StopEndpoint stops a running endpoint
 func (e *Engine) StopEndpoint(id int) {
 	e.Mutex.Lock()
-	defer e.Mutex.Unlock()
 
 	if !e.ActiveEnd[id] {
+		e.Mutex.Unlock()
 		return
 	}
 
@@ -265,6 +395,10 @@ func (e *Engine) StopEndpoint(id int) {
 	if e.Status[id] == types.StatusRunning {
 		e.Status[id] = types.StatusIdle
 	}
+	if stop, ok := e.runStop[id]; ok {
+		close(stop)
+		delete(e.runStop, id)
+	}
 
 	// Only set Running to false if no more client endpoints are active
 	if e.activeCount == 0 {
@@ -284,18 +418,27 @@ func (e *Engine) StopEndpoint(id int) {
 		}
 		delete(e.Clients, id)
 	}
+	st := e.Status[id]
+	e.Mutex.Unlock()
 
-	e.log(fmt.Sprintf("Endpoint %d stopped", id))
+	e.logFor(id, fmt.Sprintf("Endpoint %d stopped", id))
+	e.statusChanged(id, st)
 }
 
 // StopAll stops all running endpoints
 func (e *Engine) StopAll() {
 	e.Mutex.Lock()
+	var stopped []int
 	for id := range e.ActiveEnd {
+		stopped = append(stopped, id)
 		delete(e.ActiveEnd, id)
 		if e.Status[id] == types.StatusRunning {
 			e.Status[id] = types.StatusIdle
 		}
+		if stop, ok := e.runStop[id]; ok {
+			close(stop)
+			delete(e.runStop, id)
+		}
 
 		// Close listener if exists
 		if ln, ok := e.Listeners[id]; ok {
@@ -313,33 +456,41 @@ func (e *Engine) StopAll() {
 	}
 	e.activeCount = 0
 	e.Running = false
-	e.Mutex.Unlock()
 
-	// Cancel context to stop any running goroutines
+	// Cancel context to stop any running goroutines, and create a new one
+	// for future runs
 	e.Cancel()
-	e.log("All endpoints stopped")
-
-	// Create new context for future runs
 	e.Ctx, e.Cancel = context.WithCancel(context.Background())
+	e.Mutex.Unlock()
+
+	e.log("All endpoints stopped")
+	for _, id := range stopped {
+		e.statusChanged(id, e.GetStatus(id))
+	}
 }
 
 // setupListener starts a single listener
 func (e *Engine) setupListener(ep types.Endpoint) error {
 	e.Mutex.Lock()
-	defer e.Mutex.Unlock()
 
 	addr := net.JoinHostPort(ep.Address, strconv.Itoa(ep.Port))
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
+		e.Mutex.Unlock()
 		return fmt.Errorf("endpoint %d (%s): %w", ep.ID, addr, err)
 	}
 	e.Listeners[ep.ID] = ln
-	e.log(fmt.Sprintf("Endpoint %d listening on %s", ep.ID, addr))
 
 	// Create connection map entry for this server
 	if _, ok := e.Clients[ep.ID]; !ok {
 		e.Clients[ep.ID] = make(map[int]net.Conn)
 	}
+	ctx := e.Ctx
+	e.Mutex.Unlock()
+
+	e.logFor(ep.ID, fmt.Sprintf("Endpoint %d listening on %s", ep.ID, addr))
+
+	t := e.timeoutsFor(&ep)
 
 	// Start accept loop — keep accepted connections alive and drain data
 	go func(id int, listener net.Listener, ctx context.Context) {
@@ -352,57 +503,32 @@ func (e *Engine) setupListener(ep types.Endpoint) error {
 					return
 				default:
 					// Log error only if not intentionally closed
-					e.log(fmt.Sprintf("Endpoint %d listener error: %v", id, err))
+					e.emit(Event{Type: EventError, Endpoint: id, Peer: -1, Err: err,
+						Text: fmt.Sprintf("Endpoint %d listener error: %v", id, err)})
 				}
 				return
 			}
-			e.log(fmt.Sprintf("Endpoint %d accepted connection from %s", id, conn.RemoteAddr()))
+			e.emit(Event{Type: EventConnected, Endpoint: id, Peer: -1,
+				Text: fmt.Sprintf("Endpoint %d accepted connection from %s", id, conn.RemoteAddr())})
 			// Drain incoming data in background to prevent kernel buffer from filling
-			go func(c net.Conn, drainCtx context.Context) {
-				defer c.Close()
-				buf := make([]byte, 4096)
-				for {
-					select {
-					case <-drainCtx.Done():
-						return
-					default:
-						c.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
-						n, err := c.Read(buf)
-						if err != nil {
-							return
-						}
-						if n == 0 {
-							return
-						}
-					}
-				}
-			}(conn, ctx)
+			go e.receive(ctx, id, -1, e.track(conn, id, -1), t)
 		}
-	}(ep.ID, ln, e.Ctx)
+	}(ep.ID, ln, ctx)
 
 	return nil
 }
 
 // setupListeners removed in favor of single setupListener
 
-func (e *Engine) executeMessage(msg types.Message) error {
+func (e *Engine) executeMessage(ctx context.Context, s *session, msg types.Message, stats *IterationStats, stop <-chan struct{}) error {
 	fromID := msg.From
 
-	// Get endpoint-specific mutex for connection operations
-	e.Mutex.Lock()
-	epMu, ok := e.endpointMutex[fromID]
-	if !ok {
-		epMu = &sync.Mutex{}
-		e.endpointMutex[fromID] = epMu
-	}
-	e.Mutex.Unlock()
-
 	switch msg.Kind {
 	case "syn":
 		// Initiate connection
 		// Find 'To' endpoint
 		var target *types.Endpoint
-		for _, ep := range e.Config.Endpoints {
+		for _, ep := range e.scenario().Endpoints {
 			if ep.ID == msg.To {
 				target = &ep
 				break
@@ -412,34 +538,55 @@ func (e *Engine) executeMessage(msg types.Message) error {
 			return fmt.Errorf("target endpoint %d not found", msg.To)
 		}
 
+		from := e.findEndpoint(fromID)
+		t := e.timeoutsFor(from)
+
+		dialer, err := e.newDialer(from, t.connect)
+		if err != nil {
+			return fmt.Errorf("connect failed: %w", err)
+		}
+
 		addr := net.JoinHostPort(target.Address, strconv.Itoa(target.Port))
-		e.log(fmt.Sprintf("Connecting %d -> %d (%s)...", fromID, msg.To, addr))
+		if dialer.LocalAddr != nil {
+			e.logFor(fromID, fmt.Sprintf("Connecting %d -> %d (%s) from %s...", fromID, msg.To, addr, dialer.LocalAddr))
+		} else {
+			e.logFor(fromID, fmt.Sprintf("Connecting %d -> %d (%s)...", fromID, msg.To, addr))
+		}
 
 		// Network I/O outside of mutex
-		conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+		raw, err := dialer.DialContext(ctx, "tcp", addr)
 		if err != nil {
+			if isTimeout(err) {
+				err = &TimeoutError{Op: "connect", Endpoint: fromID, Peer: msg.To, Timeout: t.connect, Err: err}
+			}
+			// SO_REUSEADDR does not let a bound port reconnect to the same
+			// server while its last connection is in TIME_WAIT
+			if local, ok := dialer.LocalAddr.(*net.TCPAddr); ok && local.Port != 0 && errors.Is(err, syscall.EADDRNOTAVAIL) {
+				return fmt.Errorf("connect failed: %w (source port %d is still held by an earlier connection; use port 0 to bind only the address)", err, local.Port)
+			}
 			return fmt.Errorf("connect failed: %w", err)
 		}
+		conn := e.track(raw, fromID, msg.To)
 
-		// Store connection under endpoint mutex
-		epMu.Lock()
-		if e.Clients[fromID] == nil {
-			e.Clients[fromID] = make(map[int]net.Conn)
-		}
-		e.Clients[fromID][msg.To] = conn
-		epMu.Unlock()
+		// Store connection under session mutex
+		s.set(msg.To, conn)
 
-		e.log(fmt.Sprintf("Connected %d -> %d", fromID, msg.To))
+		e.emit(Event{Type: EventConnected, Endpoint: fromID, Peer: msg.To,
+			Text: fmt.Sprintf("Connected %d -> %d", fromID, msg.To)})
+
+		// Drain responses and enforce read/idle timeouts
+		go func() {
+			e.receive(ctx, fromID, msg.To, conn, t)
+			s.drop(msg.To, conn)
+		}()
 
 	case "data", "psh", "push":
 		// Send Data
 		// Check if we have a connection from 'From' to 'To'
-		epMu.Lock()
-		conn, ok := e.Clients[fromID][msg.To]
-		epMu.Unlock()
+		conn, ok := s.get(msg.To)
 
 		if !ok {
-			e.log(fmt.Sprintf("simulating data %d -> %d (no active conn)", fromID, msg.To))
+			e.logFor(fromID, fmt.Sprintf("simulating data %d -> %d (no active conn)", fromID, msg.To))
 			return nil
 		}
 
@@ -450,35 +597,101 @@ func (e *Engine) executeMessage(msg types.Message) error {
 		}
 
 		if len(data) > 0 {
+			t := e.timeoutsFor(e.findEndpoint(fromID))
+			if t.write > 0 {
+				conn.SetWriteDeadline(time.Now().Add(t.write))
+			}
 			_, err := conn.Write(data)
 			if err != nil {
+				if isTimeout(err) {
+					err = &TimeoutError{Op: "write", Endpoint: fromID, Peer: msg.To, Timeout: t.write, Err: err}
+				}
 				return fmt.Errorf("write failed: %w", err)
 			}
-			e.log(fmt.Sprintf("Sent %d bytes %d -> %d", len(data), fromID, msg.To))
+			stats.BytesSent += int64(len(data))
+			e.emit(Event{Type: EventSent, Endpoint: fromID, Peer: msg.To, Bytes: len(data), Payload: data,
+				Text: fmt.Sprintf("Sent %d bytes %d -> %d", len(data), fromID, msg.To)})
 		}
 
 	case "fin":
-		// Close connection
-		epMu.Lock()
-		conn, ok := e.Clients[fromID][msg.To]
-		if ok {
+		// Half-close: send FIN but keep reading whatever the peer still sends.
+		// The receive loop closes the socket once the peer closes its side.
+		conn, ok := s.get(msg.To)
+
+		if !ok {
+			e.logFor(fromID, fmt.Sprintf("simulating fin %d -> %d (no active conn)", fromID, msg.To))
+			return nil
+		}
+
+		if err := closeWrite(conn); err != nil {
+			return fmt.Errorf("half-close failed: %w", err)
+		}
+		e.emit(Event{Type: EventClosed, Endpoint: fromID, Peer: msg.To,
+			Text: fmt.Sprintf("Half-closed connection %d -> %d", fromID, msg.To)})
+
+		if drain := e.finDrain(e.findEndpoint(fromID)); drain > 0 {
+			if tc, ok := conn.(*trackedConn); ok {
+				select {
+				case <-tc.done:
+					e.logFor(fromID, fmt.Sprintf("Peer %d closed connection from %d", msg.To, fromID))
+				case <-time.After(drain):
+					e.warnFor(fromID, fmt.Sprintf("Peer %d did not close within %v, closing %d -> %d", msg.To, drain, fromID, msg.To))
+				case <-stop:
+				case <-ctx.Done():
+				}
+			}
 			conn.Close()
-			delete(e.Clients[fromID], msg.To)
 		}
-		epMu.Unlock()
 
-		if ok {
-			e.log(fmt.Sprintf("Closed connection %d -> %d", fromID, msg.To))
+	case "rst":
+		// Abortive close: SO_LINGER 0 makes Close send RST instead of FIN
+		conn, ok := s.take(msg.To)
+
+		if !ok {
+			e.logFor(fromID, fmt.Sprintf("simulating rst %d -> %d (no active conn)", fromID, msg.To))
+			return nil
+		}
+
+		if err := abort(conn); err != nil {
+			return fmt.Errorf("reset failed: %w", err)
 		}
+		e.emit(Event{Type: EventClosed, Endpoint: fromID, Peer: msg.To,
+			Text: fmt.Sprintf("Reset connection %d -> %d", fromID, msg.To)})
+
+	case "ack", "syn-ack":
+		// Handled by the kernel TCP stack
 
 	default:
+		e.warnFor(fromID, fmt.Sprintf("Unknown message kind %q %d -> %d, skipped", msg.Kind, fromID, msg.To))
 	}
 
 	return nil
 }
 
 func (e *Engine) log(msg string) {
-	ts := time.Now().Format("15:04:05")
-	line := fmt.Sprintf("[%s] %s", ts, msg)
-	e.Log.Write(line)
+	e.emit(Event{Type: EventLog, Endpoint: -1, Peer: -1, Text: msg})
+}
+
+func (e *Engine) warn(msg string) {
+	e.emit(Event{Type: EventLog, Level: LevelWarn, Endpoint: -1, Peer: -1, Text: msg})
+}
+
+// logFor logs a message about endpoint id, so it can be told apart from
+// the logs of other endpoints.
+func (e *Engine) logFor(id int, msg string) {
+	e.emit(Event{Type: EventLog, Endpoint: id, Peer: -1, Text: msg})
+}
+
+func (e *Engine) warnFor(id int, msg string) {
+	e.emit(Event{Type: EventLog, Level: LevelWarn, Endpoint: id, Peer: -1, Text: msg})
+}
+
+// Close releases the event bus and the log file. The engine must not be
+// used afterwards.
+func (e *Engine) Close() {
+	e.Events.Close()
+	if e.capture != nil {
+		e.capture.w.Close()
+	}
+	e.Log.Close()
 }
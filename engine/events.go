@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// EventType identifies what happened in an Event.
+type EventType int
+
+const (
+	EventLog       EventType = iota // Free-form message
+	EventConnected                  // Connection established (client) or accepted (server)
+	EventSent                       // Payload written
+	EventReceived                   // Payload read
+	EventClosed                     // Connection closed, half-closed or reset
+	EventError                      // Operation failed
+	EventStatus                     // Endpoint status changed
+	EventMessage                    // Scenario message about to be executed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventLog:
+		return "log"
+	case EventConnected:
+		return "connected"
+	case EventSent:
+		return "sent"
+	case EventReceived:
+		return "received"
+	case EventClosed:
+		return "closed"
+	case EventError:
+		return "error"
+	case EventStatus:
+		return "status"
+	case EventMessage:
+		return "message"
+	}
+	return "unknown"
+}
+
+// Event is a single engine occurrence. Text is the human-readable form
+// written to the log.
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Level    Level // Zero uses the default for Type
+	Endpoint int   // -1 when not tied to an endpoint
+	Peer     int   // -1 when unknown
+	Bytes    int
+	Payload  []byte // Copy of the data for EventSent/EventReceived
+	Status   types.EndpointStatus
+	Message  int // Index among the sender's messages for EventMessage
+	Err      error
+	Text     string
+}
+
+// Policy decides what Publish does when a subscriber's buffer is full.
+type Policy int
+
+const (
+	DropNewest Policy = iota // Discard the event being published
+	DropOldest               // Discard the oldest buffered event
+	Block                    // Wait until the subscriber has room
+)
+
+// Subscription receives events from a Bus on C.
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	policy  Policy
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+	bus     *Bus
+}
+
+// Dropped returns how many events this subscriber has lost.
+func (s *Subscription) Dropped() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.dropped.Load()
+}
+
+// stop releases publishers blocked on this subscriber.
+func (s *Subscription) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Close unsubscribes; C is closed once pending publishes have returned.
+func (s *Subscription) Close() {
+	if s == nil {
+		return
+	}
+	s.bus.unsubscribe(s)
+}
+
+// Bus fans engine events out to any number of subscribers.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a subscriber with a buffer of size events. Block
+// subscribers slow the engine down when they fall behind, so they must not
+// call back into the engine while handling an event.
+func (b *Bus) Subscribe(size int, policy Policy) *Subscription {
+	if size <= 0 {
+		size = 1
+	}
+	ch := make(chan Event, size)
+	s := &Subscription{C: ch, ch: ch, policy: policy, done: make(chan struct{}), bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+func (b *Bus) unsubscribe(s *Subscription) {
+	// Wake any publisher blocked on this subscriber before taking the lock
+	s.stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Publish delivers ev to every subscriber according to its policy.
+func (b *Bus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	for s := range b.subs {
+		switch s.policy {
+		case Block:
+			select {
+			case s.ch <- ev:
+			case <-s.done:
+			}
+		case DropOldest:
+			for {
+				select {
+				case s.ch <- ev:
+				default:
+					select {
+					case <-s.ch:
+						s.dropped.Add(1)
+					default:
+					}
+					continue
+				}
+				break
+			}
+		default:
+			select {
+			case s.ch <- ev:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// Close closes every subscription channel. Later publishes are ignored.
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	// Release blocked publishers first so the write lock can be taken
+	b.mu.RLock()
+	for s := range b.subs {
+		s.stop()
+	}
+	b.mu.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for s := range b.subs {
+		close(s.ch)
+		delete(b.subs, s)
+	}
+}
+
+// emit writes ev to the log and publishes it. It must not be called while
+// holding e.Mutex, since Block subscribers may take a while to accept it.
+func (e *Engine) emit(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.Level == 0 {
+		ev.Level = e.Log.levelFor(ev.Type)
+	}
+	e.stats.event(ev)
+	if ev.Text != "" {
+		if err := e.Log.writeEvent(ev); err != nil {
+			defer e.emit(rotateFailed(err))
+		}
+	}
+	e.Events.Publish(ev)
+}
+
+// statusChanged publishes an EventStatus for id.
+func (e *Engine) statusChanged(id int, st types.EndpointStatus) {
+	e.emit(Event{Type: EventStatus, Endpoint: id, Peer: -1, Status: st})
+}
@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samaelod/nabu/types"
+)
+
+const (
+	// maxLatencySamples bounds the session latencies kept for percentiles.
+	maxLatencySamples = 10000
+	// schedulerTick is the resolution of the arrival schedule.
+	schedulerTick = 10 * time.Millisecond
+)
+
+// LoadStats is a snapshot of the open-model load scheduler.
+type LoadStats struct {
+	Running   bool
+	Stage     int     // Current stage index, -1 before start
+	Rate      float64 // Current target arrivals/s
+	Started   int
+	Completed int
+	Failed    int
+	Dropped   int // Arrivals skipped because MaxSessions were active
+	Active    int
+
+	// Session latencies measured from the scheduled start rather than the
+	// actual start, so scheduler lag is not hidden (coordinated omission).
+	Latencies []time.Duration
+}
+
+// HasLoadProfile reports whether the scenario defines a load profile.
+func (e *Engine) HasLoadProfile() bool {
+	return len(e.scenario().Globals.Load.Stages) > 0
+}
+
+// LoadRunning reports whether the load scheduler is active.
+func (e *Engine) LoadRunning() bool {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	return e.loadCancel != nil
+}
+
+// StartLoad starts the load profile from Globals.Load. Each arrival replays
+// one template endpoint's trace once on its own connections.
+func (e *Engine) StartLoad() error {
+	if err := e.startLoad(); err != nil {
+		e.emit(Event{Type: EventError, Endpoint: -1, Peer: -1, Err: err,
+			Text: fmt.Sprintf("Load profile error: %v", err)})
+		return err
+	}
+	return nil
+}
+
+func (e *Engine) startLoad() error {
+	cfg := e.scenario()
+	profile := cfg.Globals.Load
+	if len(profile.Stages) == 0 {
+		return errors.New("no load profile configured")
+	}
+
+	templates := profile.Endpoints
+	if len(templates) == 0 {
+		for _, ep := range cfg.Endpoints {
+			if ep.Kind != "server" && len(cfg.MessagesByFrom[ep.ID]) > 0 {
+				templates = append(templates, ep.ID)
+			}
+		}
+	}
+	for _, id := range templates {
+		ep := e.findEndpoint(id)
+		if ep == nil {
+			return fmt.Errorf("load profile: endpoint %d not found", id)
+		}
+		if ep.Kind == "server" {
+			return fmt.Errorf("load profile: endpoint %d is a server", id)
+		}
+	}
+	if len(templates) == 0 {
+		return errors.New("load profile: no client endpoints with messages")
+	}
+
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+
+	if e.loadCancel != nil {
+		return errors.New("load profile already running")
+	}
+
+	ctx, cancel := context.WithCancel(e.Ctx)
+	e.loadCancel = cancel
+	e.load = LoadStats{Running: true, Stage: -1}
+	e.Running = true
+
+	go e.runLoad(ctx, profile, templates)
+
+	return nil
+}
+
+// StopLoad stops scheduling new sessions and cancels the active ones.
+func (e *Engine) StopLoad() {
+	e.Mutex.Lock()
+	cancel := e.loadCancel
+	e.Mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// LoadStats returns a snapshot of the load scheduler statistics.
+func (e *Engine) LoadStats() LoadStats {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+
+	snap := e.load
+	snap.Latencies = append([]time.Duration(nil), e.load.Latencies...)
+	return snap
+}
+
+func (e *Engine) runLoad(ctx context.Context, profile types.LoadProfile, templates []int) {
+	start := time.Now()
+	stage := -1
+	arrivals := 0
+
+	var sessions sync.WaitGroup
+
+	e.log(fmt.Sprintf("Load profile started: %d stages, templates %v", len(profile.Stages), templates))
+
+	defer func() {
+		// Let in-flight sessions finish before reporting the profile as done
+		sessions.Wait()
+
+		e.Mutex.Lock()
+		cancel := e.loadCancel
+		e.loadCancel = nil
+		e.load.Running = false
+		if e.activeCount == 0 {
+			e.Running = false
+		}
+		e.Mutex.Unlock()
+
+		if cancel != nil {
+			cancel()
+		}
+	}()
+
+	// Arrivals accumulate as fractional credit per tick, so rates below one
+	// session per tick are honoured without sleeping for 1/rate up front.
+	credit := 0.0
+	for tick := 0; ; tick++ {
+		at := start.Add(time.Duration(tick) * schedulerTick)
+		rate, idx, done := rateAt(profile, at.Sub(start))
+		if done {
+			e.log("Load profile finished")
+			return
+		}
+
+		if idx != stage {
+			stage = idx
+			e.log(fmt.Sprintf("Load stage %d/%d: %.1f sessions/s for %dms",
+				idx+1, len(profile.Stages), profile.Stages[idx].Rate, profile.Stages[idx].Duration))
+		}
+
+		e.Mutex.Lock()
+		e.load.Stage = idx
+		e.load.Rate = rate
+		e.Mutex.Unlock()
+
+		if wait := time.Until(at); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				e.log("Load profile stopped")
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			e.log("Load profile stopped")
+			return
+		default:
+		}
+
+		// Sessions keep their intended start time even if the scheduler is late
+		credit += rate * schedulerTick.Seconds()
+		for credit >= 1 {
+			credit--
+			id := templates[arrivals%len(templates)]
+			arrivals++
+			e.startSession(ctx, &sessions, id, at, profile.MaxSessions)
+		}
+	}
+}
+
+// rateAt returns the target arrival rate of p at elapsed time into it,
+// the active stage index, and whether the profile has finished.
+func rateAt(p types.LoadProfile, elapsed time.Duration) (float64, int, bool) {
+	prev := p.StartRate
+	var offset time.Duration
+
+	for i, st := range p.Stages {
+		d := ms(st.Duration)
+		if elapsed < offset+d {
+			if st.Shape == "step" || d == 0 {
+				return st.Rate, i, false
+			}
+			frac := float64(elapsed-offset) / float64(d)
+			return prev + (st.Rate-prev)*frac, i, false
+		}
+		offset += d
+		prev = st.Rate
+	}
+
+	return 0, len(p.Stages) - 1, true
+}
+
+// startSession launches one load session unless maxSessions are active.
+func (e *Engine) startSession(ctx context.Context, wg *sync.WaitGroup, id int, scheduled time.Time, maxSessions int) {
+	e.Mutex.Lock()
+	if maxSessions > 0 && e.load.Active >= maxSessions {
+		e.load.Dropped++
+		e.Mutex.Unlock()
+		return
+	}
+	e.load.Active++
+	e.load.Started++
+	e.Mutex.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		s := e.newSession(id)
+		stats := IterationStats{Iteration: 1, Start: time.Now()}
+		completed := e.playTrace(ctx, s, &stats, nil, func() bool { return true })
+		s.closeAll()
+
+		latency := time.Since(scheduled)
+
+		e.Mutex.Lock()
+		defer e.Mutex.Unlock()
+		e.load.Active--
+		if !completed {
+			// Cancelled sessions say nothing about the server's latency
+			return
+		}
+		if stats.Errors > 0 {
+			e.load.Failed++
+		} else {
+			e.load.Completed++
+		}
+		e.load.Latencies = append(e.load.Latencies, latency)
+		if len(e.load.Latencies) > maxLatencySamples {
+			e.load.Latencies = e.load.Latencies[len(e.load.Latencies)-maxLatencySamples:]
+		}
+	}()
+}
@@ -1,170 +1,318 @@
 package engine
 
 import (
-	"os"
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/samaelod/nabu/config"
 )
 
 const (
 	defaultLogLines      = 1000
-	defaultBatchSize     = 10
 	defaultFlushInterval = 100 * time.Millisecond
 )
 
+// Level is the severity of a log entry.
+type Level int
+
+// Levels start at one so a zero Event.Level means "default for the type".
+const (
+	LevelDebug Level = iota + 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "info"
+}
+
+// ParseLevel converts a level name; empty and unknown names map to LevelInfo.
+func ParseLevel(s string) Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug
+	case "warn", "warning":
+		return LevelWarn
+	case "error":
+		return LevelError
+	}
+	return LevelInfo
+}
+
+// defaultLevel is the level of an event that does not set one.
+func defaultLevel(t EventType) Level {
+	switch t {
+	case EventReceived, EventStatus:
+		return LevelDebug
+	case EventError:
+		return LevelError
+	}
+	return LevelInfo
+}
+
+// levelFor is the level of an event of type t that does not set one. With
+// dumps on, received payloads are logged at info like sent ones, so both
+// directions show at the default threshold.
+func (l *Logger) levelFor(t EventType) Level {
+	if t == EventReceived && l != nil && l.opts.Dumps {
+		return LevelInfo
+	}
+	return defaultLevel(t)
+}
+
+// LoggerOptions configures a Logger.
+type LoggerOptions struct {
+	Path       string        // Log file, empty for memory only
+	Lines      int           // In-memory ring buffer size
+	Level      Level         // Entries below this level are discarded
+	JSON       bool          // Write JSON lines to the file instead of text
+	MaxSize    int64         // Rotate when the file exceeds this many bytes (0 = never)
+	MaxAge     time.Duration // Rotate files older than this and prune old backups (0 = never)
+	MaxBackups int           // Rotated files kept (0 = keep all)
+	Dumps      bool          // Append hex+ASCII dumps of sent and received payloads
+}
+
+// LoggerOptionsFor builds logger options for path from the app config.
+func LoggerOptionsFor(appConfig *config.Config, path string) LoggerOptions {
+	return LoggerOptions{
+		Path:       path,
+		Lines:      appConfig.LogLines,
+		Level:      ParseLevel(appConfig.LogLevel),
+		JSON:       appConfig.LogFormat == "json",
+		MaxSize:    int64(appConfig.LogMaxSizeMB) * 1024 * 1024,
+		MaxAge:     time.Duration(appConfig.LogMaxAgeHours) * time.Hour,
+		MaxBackups: appConfig.LogMaxBackups,
+		Dumps:      appConfig.Verbose,
+	}
+}
+
+// LogEntry is one line of the in-memory ring buffer.
+type LogEntry struct {
+	Time     time.Time
+	Level    Level
+	Endpoint int // -1 for messages about no endpoint
+	Text     string
+}
+
+func (e LogEntry) String() string {
+	return "[" + e.Time.Format("15:04:05") + "] " + e.Text
+}
+
+// jsonEntry is the JSON-lines file format.
+type jsonEntry struct {
+	Time     string `json:"ts"`
+	Level    string `json:"level"`
+	Type     string `json:"type,omitempty"`
+	Endpoint *int   `json:"endpoint,omitempty"`
+	Peer     *int   `json:"peer,omitempty"`
+	Bytes    int    `json:"bytes,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Payload  string `json:"payload,omitempty"` // Hex, only with Dumps
+	Msg      string `json:"msg"`
+}
+
 type Logger struct {
 	mu       sync.Mutex
-	lines    []string
+	lines    []LogEntry
 	capacity int
 	head     int
 	count    int
 
-	filePath string
-	file     *os.File
-	ch       chan string
-	closed   bool
+	opts   LoggerOptions
+	file   *rotatingFile
+	buf    *bufio.Writer
+	done   chan struct{}
+	closed bool
 }
 
 func NewLogger(filePath string, capacity int) *Logger {
-	if capacity <= 0 {
-		capacity = defaultLogLines
+	return NewLoggerWithOptions(LoggerOptions{Path: filePath, Lines: capacity, Level: LevelInfo})
+}
+
+func NewLoggerWithOptions(opts LoggerOptions) *Logger {
+	if opts.Lines <= 0 {
+		opts.Lines = defaultLogLines
 	}
 
 	l := &Logger{
-		lines:    make([]string, capacity),
-		capacity: capacity,
-		filePath: filePath,
-		ch:       make(chan string, 100),
+		lines:    make([]LogEntry, opts.Lines),
+		capacity: opts.Lines,
+		opts:     opts,
+		done:     make(chan struct{}),
 	}
 
-	if err := l.openFile(); err != nil {
+	if opts.Path == "" {
 		return l
 	}
 
-	go l.writer()
+	f, err := openRotatingFile(opts.Path, opts.MaxSize, opts.MaxAge, opts.MaxBackups)
+	if err != nil {
+		return l
+	}
+	l.file = f
+	l.buf = bufio.NewWriter(f)
+
+	go l.flusher()
 
 	return l
 }
 
-func (l *Logger) openFile() error {
-	if l.filePath == "" {
-		return nil
-	}
-
-	dir := ""
-	// Extract directory from file path
-	for i := len(l.filePath) - 1; i >= 0; i-- {
-		if l.filePath[i] == '/' || l.filePath[i] == '\\' {
-			dir = l.filePath[:i]
-			break
-		}
+// Write logs a plain message at info level.
+func (l *Logger) Write(msg string) {
+	if l == nil {
+		return
 	}
-	if dir != "" {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return err
-		}
+	if err := l.writeEvent(Event{Type: EventLog, Time: time.Now(), Level: LevelInfo, Endpoint: -1, Peer: -1, Text: msg}); err != nil {
+		l.writeEvent(rotateFailed(err))
 	}
+}
 
-	f, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
-	if err != nil {
-		return err
-	}
-	l.file = f
-	return nil
+// rotateFailed is the event reporting that the log file could not be
+// rotated.
+func rotateFailed(err error) Event {
+	return Event{Type: EventError, Time: time.Now(), Endpoint: -1, Peer: -1, Err: err,
+		Text: fmt.Sprintf("Log rotation failed, still writing to the current file: %v", err)}
 }
 
-func (l *Logger) Write(msg string) {
-	if l == nil {
-		return
+// writeEvent logs ev if it is at or above the threshold. It returns why the
+// log file could not be rotated before ev was written, if it could not.
+func (l *Logger) writeEvent(ev Event) error {
+	if ev.Level == 0 {
+		ev.Level = l.levelFor(ev.Type)
+	}
+	if l == nil || ev.Level < l.opts.Level {
+		return nil
 	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	if l.closed {
-		return
+		return nil
 	}
 
-	l.lines[l.head] = msg
+	if !l.opts.Dumps {
+		ev.Payload = nil
+	}
+
+	entry := LogEntry{Time: ev.Time, Level: ev.Level, Endpoint: ev.Endpoint, Text: ev.Text}
+	if len(ev.Payload) > 0 {
+		entry.Text += "\n" + strings.TrimSuffix(hex.Dump(ev.Payload), "\n")
+	}
+	l.lines[l.head] = entry
 	l.head = (l.head + 1) % l.capacity
 	if l.count < l.capacity {
 		l.count++
 	}
 
-	select {
-	case l.ch <- msg:
-	default:
+	// Every line reaches the file; the flusher bounds how long it stays buffered
+	if l.buf == nil {
+		return nil
+	}
+	var line []byte
+	if l.opts.JSON {
+		line = encodeJSON(ev)
+	} else {
+		line = []byte(entry.String())
+	}
+	line = append(line, '\n')
+
+	// Rotate between lines, so none is split across two files
+	var err error
+	if l.file.due(l.buf.Buffered(), len(line)) {
+		l.buf.Flush()
+		err = l.file.rotate()
+	}
+	l.buf.Write(line)
+	return err
+}
+
+func encodeJSON(ev Event) []byte {
+	je := jsonEntry{
+		Time:  ev.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Level: ev.Level.String(),
+		Bytes: ev.Bytes,
+		Msg:   ev.Text,
+	}
+	if ev.Type != EventLog {
+		je.Type = ev.Type.String()
+	}
+	if ev.Endpoint >= 0 {
+		id := ev.Endpoint
+		je.Endpoint = &id
 	}
+	if ev.Peer >= 0 {
+		peer := ev.Peer
+		je.Peer = &peer
+	}
+	if ev.Err != nil {
+		je.Error = ev.Err.Error()
+	}
+	je.Payload = hex.EncodeToString(ev.Payload)
+	data, _ := json.Marshal(je)
+	return data
 }
 
 func (l *Logger) ReadAll() string {
+	var result []byte
+	for _, e := range l.Entries() {
+		result = append(result, e.String()...)
+		result = append(result, '\n')
+	}
+	return string(result)
+}
+
+// Entries returns the lines of the ring buffer, oldest first.
+func (l *Logger) Entries() []LogEntry {
 	if l == nil {
-		return ""
+		return nil
 	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if l.count == 0 {
-		return ""
-	}
-
 	start := 0
 	if l.count >= l.capacity {
 		start = l.head
 	}
 
-	var result []byte
+	entries := make([]LogEntry, 0, l.count)
 	for i := 0; i < l.count; i++ {
-		idx := (start + i) % l.capacity
-		if l.lines[idx] != "" {
-			result = append(result, l.lines[idx]...)
-			result = append(result, '\n')
+		if e := l.lines[(start+i)%l.capacity]; e.Text != "" {
+			entries = append(entries, e)
 		}
 	}
-
-	return string(result)
-}
-
-func (l *Logger) Chan() <-chan string {
-	if l == nil {
-		return nil
-	}
-	return l.ch
+	return entries
 }
 
-func (l *Logger) writer() {
-	batch := make([]string, 0, defaultBatchSize)
+func (l *Logger) flusher() {
 	ticker := time.NewTicker(defaultFlushInterval)
 	defer ticker.Stop()
 
-	flush := func() {
-		if len(batch) == 0 || l.file == nil {
-			return
-		}
-
-		l.mu.Lock()
-		defer l.mu.Unlock()
-
-		for _, msg := range batch {
-			l.file.WriteString(msg + "\n")
-		}
-		batch = batch[:0]
-	}
-
 	for {
 		select {
-		case msg, ok := <-l.ch:
-			if !ok {
-				flush()
-				return
-			}
-			batch = append(batch, msg)
-			if len(batch) >= defaultBatchSize {
-				flush()
-			}
+		case <-l.done:
+			return
 		case <-ticker.C:
-			flush()
+			l.mu.Lock()
+			if !l.closed {
+				l.buf.Flush()
+			}
+			l.mu.Unlock()
 		}
 	}
 }
@@ -182,9 +330,10 @@ func (l *Logger) Close() {
 	}
 	l.closed = true
 
-	close(l.ch)
+	close(l.done)
 
 	if l.file != nil {
+		l.buf.Flush()
 		l.file.Close()
 	}
 }
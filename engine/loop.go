@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// loopSettings controls how often a client endpoint repeats its trace.
+type loopSettings struct {
+	iterations int           // 0 = unlimited when duration is set, otherwise once
+	duration   time.Duration // 0 = no wall-clock limit
+	think      time.Duration // pause between iterations
+}
+
+// loopFor resolves the loop settings for ep, endpoint values overriding Globals.
+func (e *Engine) loopFor(ep *types.Endpoint) loopSettings {
+	g := e.scenario().Globals
+	l := loopSettings{
+		iterations: g.Iterations,
+		duration:   ms(g.Duration),
+		think:      ms(g.ThinkTime),
+	}
+
+	if ep != nil {
+		if ep.Iterations > 0 {
+			l.iterations = ep.Iterations
+		}
+		if ep.Duration > 0 {
+			l.duration = ms(ep.Duration)
+		}
+		if ep.ThinkTime > 0 {
+			l.think = ms(ep.ThinkTime)
+		}
+	}
+
+	return l
+}
+
+// done reports whether no further iteration should start after iter
+// iterations have completed in elapsed time.
+func (l loopSettings) done(iter int, elapsed time.Duration) bool {
+	if l.duration > 0 {
+		if elapsed >= l.duration {
+			return true
+		}
+		return l.iterations > 0 && iter >= l.iterations
+	}
+	return iter >= max(l.iterations, 1)
+}
@@ -0,0 +1,57 @@
+package engine
+
+import "time"
+
+// maxIterationHistory bounds how many iterations are kept per endpoint.
+const maxIterationHistory = 100
+
+// IterationStats describes one pass of a client endpoint through its trace.
+type IterationStats struct {
+	Iteration int
+	Start     time.Time
+	Duration  time.Duration
+	Messages  int
+	Errors    int
+	BytesSent int64
+}
+
+// EndpointMetrics aggregates the iterations run by one endpoint.
+type EndpointMetrics struct {
+	Iterations int
+	Errors     int
+	BytesSent  int64
+	Last       []IterationStats // Most recent iterations, oldest first
+}
+
+func (e *Engine) recordIteration(id int, st IterationStats) {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+
+	m, ok := e.metrics[id]
+	if !ok {
+		m = &EndpointMetrics{}
+		e.metrics[id] = m
+	}
+
+	m.Iterations++
+	m.Errors += st.Errors
+	m.BytesSent += st.BytesSent
+	m.Last = append(m.Last, st)
+	if len(m.Last) > maxIterationHistory {
+		m.Last = m.Last[len(m.Last)-maxIterationHistory:]
+	}
+}
+
+// Metrics returns a snapshot of the iteration metrics for an endpoint.
+func (e *Engine) Metrics(id int) EndpointMetrics {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+
+	m, ok := e.metrics[id]
+	if !ok {
+		return EndpointMetrics{}
+	}
+	snap := *m
+	snap.Last = append([]IterationStats(nil), m.Last...)
+	return snap
+}
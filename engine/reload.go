@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// Diff describes how a reloaded scenario differs from the running one.
+type Diff struct {
+	Globals bool     // Globals changed, so every endpoint is affected
+	Added   []int    // Endpoint IDs only in the new scenario
+	Removed []int    // Endpoint IDs only in the old scenario
+	Changed []int    // Endpoints whose settings or messages changed, or that send to a changed endpoint
+	Details []string // One line per change, for the log
+}
+
+// Empty reports whether the scenarios behave the same.
+func (d Diff) Empty() bool {
+	return !d.Globals && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Summary is a one-line description of d, e.g. "endpoints +1 -0 ~2".
+func (d Diff) Summary() string {
+	if d.Empty() {
+		return "no changes"
+	}
+	s := fmt.Sprintf("endpoints +%d -%d ~%d", len(d.Added), len(d.Removed), len(d.Changed))
+	if d.Globals {
+		s += ", globals changed"
+	}
+	return s
+}
+
+// DiffConfigs compares two scenarios endpoint by endpoint.
+func DiffConfigs(old, cfg *types.Config) Diff {
+	var d Diff
+
+	if fields := changedFields(old.Globals, cfg.Globals); len(fields) > 0 {
+		d.Globals = true
+		d.Details = append(d.Details, "~ globals: "+strings.Join(fields, ", "))
+	}
+
+	oldEps, newEps := endpointsByID(old), endpointsByID(cfg)
+	oldMsgs, newMsgs := messagesBySender(old), messagesBySender(cfg)
+
+	ids := make([]int, 0, len(oldEps)+len(newEps))
+	for id := range oldEps {
+		ids = append(ids, id)
+	}
+	for id := range newEps {
+		if _, ok := oldEps[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	// Endpoints whose address or settings changed, which affects their peers
+	moved := make(map[int]bool)
+	changed := make(map[int]bool)
+	for _, id := range ids {
+		o, inOld := oldEps[id]
+		n, inNew := newEps[id]
+		switch {
+		case !inOld:
+			d.Added = append(d.Added, id)
+			d.Details = append(d.Details, fmt.Sprintf("+ endpoint %d (%s %s:%d, %d messages)",
+				id, n.Kind, n.Address, n.Port, len(newMsgs[id])))
+		case !inNew:
+			d.Removed = append(d.Removed, id)
+			moved[id] = true
+			d.Details = append(d.Details, fmt.Sprintf("- endpoint %d (%s %s:%d)", id, o.Kind, o.Address, o.Port))
+		default:
+			if fields := changedFields(o, n); len(fields) > 0 {
+				moved[id] = true
+				changed[id] = true
+				d.Details = append(d.Details, fmt.Sprintf("~ endpoint %d: %s", id, strings.Join(fields, ", ")))
+			}
+			if !reflect.DeepEqual(oldMsgs[id], newMsgs[id]) {
+				changed[id] = true
+				d.Details = append(d.Details, fmt.Sprintf("~ endpoint %d: messages changed (%d -> %d)",
+					id, len(oldMsgs[id]), len(newMsgs[id])))
+			}
+		}
+	}
+
+	// Clients sending to a moved endpoint have to reconnect
+	for _, id := range ids {
+		if _, ok := newEps[id]; !ok || changed[id] {
+			continue
+		}
+		for _, m := range newMsgs[id] {
+			if moved[m.To] {
+				changed[id] = true
+				d.Details = append(d.Details, fmt.Sprintf("~ endpoint %d: sends to changed endpoint %d", id, m.To))
+				break
+			}
+		}
+	}
+	for _, id := range ids {
+		if changed[id] {
+			d.Changed = append(d.Changed, id)
+		}
+	}
+
+	return d
+}
+
+func endpointsByID(cfg *types.Config) map[int]types.Endpoint {
+	eps := make(map[int]types.Endpoint, len(cfg.Endpoints))
+	for _, ep := range cfg.Endpoints {
+		if _, dup := eps[ep.ID]; !dup {
+			eps[ep.ID] = ep
+		}
+	}
+	return eps
+}
+
+func messagesBySender(cfg *types.Config) map[int][]types.Message {
+	msgs := make(map[int][]types.Message)
+	for _, m := range cfg.Messages {
+		msgs[m.From] = append(msgs[m.From], m)
+	}
+	return msgs
+}
+
+// changedFields names the fields of two structs of the same type that differ,
+// using their Lua keys.
+func changedFields(a, b any) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var out []string
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("lua"), ",")
+			if name == "" {
+				name = va.Type().Field(i).Name
+			}
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Reload switches the engine to cfg without stopping endpoints the change
+// does not affect. Changed and removed endpoints are stopped, and changed
+// servers that were listening start again on their new settings. When the
+// globals changed it returns false and leaves the engine untouched, since
+// timeouts, pools and load profiles are fixed for the engine's lifetime.
+func (e *Engine) Reload(cfg *types.Config) (Diff, bool) {
+	d := DiffConfigs(e.scenario(), cfg)
+	if d.Globals {
+		return d, false
+	}
+	if cfg.MessagesByFrom == nil {
+		cfg.IndexMessages()
+	}
+
+	var restart []int
+	for _, id := range slices.Concat(d.Changed, d.Removed) {
+		if !e.IsRunning(id) {
+			continue
+		}
+		ep := e.findEndpoint(id)
+		e.StopEndpoint(id)
+		if ep != nil && ep.Kind == "server" && slices.Contains(d.Changed, id) {
+			restart = append(restart, id)
+		}
+	}
+
+	e.cfgMu.Lock()
+	e.Config = cfg
+	e.cfgMu.Unlock()
+
+	e.log("Scenario reloaded: " + d.Summary())
+	for _, line := range d.Details {
+		e.log("  " + line)
+	}
+
+	for _, id := range restart {
+		if ep := e.findEndpoint(id); ep != nil && ep.Kind == "server" {
+			e.StartEndpoint(id)
+		}
+	}
+	return d, true
+}
+
+// ReloadFailed logs why a changed scenario could not be loaded; the engine
+// keeps running the previous one.
+func (e *Engine) ReloadFailed(err error) {
+	e.emit(Event{Type: EventLog, Level: LevelWarn, Endpoint: -1, Peer: -1, Err: err,
+		Text: fmt.Sprintf("Scenario reload failed, keeping the running scenario: %v", err)})
+}
@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rotateRetry is how long writes go on without rotating after a rotation
+// failed, so a lasting problem is not retried and reported on every line.
+const rotateRetry = time.Minute
+
+// rotatingFile is an append-only file that renames itself to path.1,
+// path.2, ... when it grows past maxSize or becomes older than maxAge.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	file    *os.File
+	size    int64
+	opened  time.Time
+	retryAt time.Time // No rotation before this, set when one failed
+}
+
+func openRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &rotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	// A file left over from an earlier run may already be due. If it cannot
+	// be rotated now, lines are appended to it until the next attempt.
+	if r.due(0, 0) {
+		r.rotate()
+	}
+
+	return r, nil
+}
+
+// open opens path, replacing the current file only once that succeeded.
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if r.file != nil {
+		r.file.Close()
+	}
+	r.file = f
+	r.size = info.Size()
+	r.opened = info.ModTime()
+	if r.size == 0 {
+		r.opened = time.Now()
+	}
+	return nil
+}
+
+// due reports whether the file must be rotated before next more bytes are
+// written, pending being buffered bytes not written yet. Empty files never
+// are.
+func (r *rotatingFile) due(pending, next int) bool {
+	size := r.size + int64(pending)
+	if size == 0 || time.Now().Before(r.retryAt) {
+		return false
+	}
+	if r.maxSize > 0 && size+int64(next) > r.maxSize {
+		return true
+	}
+	return r.maxAge > 0 && time.Since(r.opened) > r.maxAge
+}
+
+// Write appends p without rotating; the caller rotates between complete
+// lines, see due.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
+
+// rotate moves the current file to path.1, shifting path.N to path.N+1 to
+// make room, reopens path, then prunes backups beyond maxBackups or older
+// than maxAge. The current file is moved aside before any backup is
+// touched and put back if shifting fails. Only backups up to the first gap
+// are shifted, so a retry after a partial shift fills the gap instead of
+// pushing older backups out. The current file stays open until its
+// replacement is, so when rotating fails writes go on to the file they went
+// to before.
+func (r *rotatingFile) rotate() error {
+	fail := func(err error) error {
+		r.retryAt = time.Now().Add(rotateRetry)
+		return fmt.Errorf("rotate %s: %w", r.path, err)
+	}
+
+	aside := r.path + ".rotating"
+	if err := os.Rename(r.path, aside); err != nil {
+		return fail(err)
+	}
+
+	// Backups from path.1 up to the first gap move up by one
+	last := 0
+	for _, n := range r.backups() {
+		if n != last+1 {
+			break
+		}
+		last = n
+	}
+	for n := last; n >= 1; n-- {
+		if err := os.Rename(r.backupName(n), r.backupName(n+1)); err != nil {
+			os.Rename(aside, r.path)
+			return fail(err)
+		}
+	}
+	if err := os.Rename(aside, r.backupName(1)); err != nil {
+		os.Rename(aside, r.path)
+		return fail(err)
+	}
+
+	if err := r.open(); err != nil {
+		return fail(err)
+	}
+	r.prune()
+	return nil
+}
+
+func (r *rotatingFile) backupName(n int) string {
+	return r.path + "." + strconv.Itoa(n)
+}
+
+// backups returns the existing backup numbers in ascending order.
+func (r *rotatingFile) backups() []int {
+	matches, _ := filepath.Glob(r.path + ".*")
+	var nums []int
+	for _, m := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(m, r.path+"."))
+		if err == nil && n > 0 {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+func (r *rotatingFile) prune() {
+	for _, n := range r.backups() {
+		name := r.backupName(n)
+		if r.maxBackups > 0 && n > r.maxBackups {
+			os.Remove(name)
+			continue
+		}
+		if r.maxAge > 0 {
+			if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > r.maxAge {
+				os.Remove(name)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"net"
+	"sync"
+)
+
+// session is the connection table used while playing a trace. Endpoint runs
+// share e.Clients[id] so StopEndpoint can reach their connections; load
+// sessions own a private table so many can replay the same endpoint at once.
+type session struct {
+	e      *Engine
+	id     int // Endpoint whose messages are played
+	mu     *sync.Mutex
+	conns  map[int]net.Conn // nil for endpoint sessions
+	errors bool             // Mark the endpoint as errored on failures
+}
+
+// endpointSession returns the session used by StartEndpoint runs of id.
+func (e *Engine) endpointSession(id int) *session {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+
+	epMu, ok := e.endpointMutex[id]
+	if !ok {
+		epMu = &sync.Mutex{}
+		e.endpointMutex[id] = epMu
+	}
+	return &session{e: e, id: id, mu: epMu, errors: true}
+}
+
+func (e *Engine) newSession(id int) *session {
+	return &session{e: e, id: id, mu: &sync.Mutex{}, conns: make(map[int]net.Conn)}
+}
+
+// table returns the connection map; callers must hold s.mu.
+func (s *session) table() map[int]net.Conn {
+	if s.conns != nil {
+		return s.conns
+	}
+	if s.e.Clients[s.id] == nil {
+		s.e.Clients[s.id] = make(map[int]net.Conn)
+	}
+	return s.e.Clients[s.id]
+}
+
+func (s *session) get(to int) (net.Conn, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn, ok := s.table()[to]
+	return conn, ok
+}
+
+func (s *session) set(to int, conn net.Conn) {
+	s.mu.Lock()
+	s.table()[to] = conn
+	s.mu.Unlock()
+}
+
+// take removes and returns the connection to peer to.
+func (s *session) take(to int) (net.Conn, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.table()
+	conn, ok := t[to]
+	if ok {
+		delete(t, to)
+	}
+	return conn, ok
+}
+
+// drop forgets conn if it is still the active connection to peer to.
+func (s *session) drop(to int, conn net.Conn) {
+	s.mu.Lock()
+	t := s.table()
+	if t[to] == conn {
+		delete(t, to)
+	}
+	s.mu.Unlock()
+}
+
+// closeAll closes every connection in the session.
+func (s *session) closeAll() {
+	s.mu.Lock()
+	t := s.table()
+	for to, conn := range t {
+		conn.Close()
+		delete(t, to)
+	}
+	s.mu.Unlock()
+}
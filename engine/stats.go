@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxResponseSamples bounds the response latencies kept for percentiles.
+const maxResponseSamples = 10000
+
+// RunStats is a snapshot of the live counters of a run. Counters are totals
+// since the engine was created; rates come from comparing two snapshots.
+type RunStats struct {
+	Connections int64 // Open connections
+	Messages    int64 // Messages executed
+	BytesSent   int64
+	BytesRecv   int64
+	Errors      int64
+
+	// Time from the first unanswered write on a connection to the next
+	// data read from it, oldest first
+	Latencies []time.Duration
+
+	Progress map[int]Progress // By endpoint ID, for endpoints that send
+}
+
+// Progress is how far an endpoint is through its messages.
+type Progress struct {
+	Message int // Messages of the current pass executed so far
+	Total   int
+}
+
+// runStats collects RunStats from events and connections.
+type runStats struct {
+	conns, messages, sent, recv, errors atomic.Int64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	progress  map[int]int
+}
+
+func (s *runStats) event(ev Event) {
+	switch ev.Type {
+	case EventMessage:
+		s.messages.Add(1)
+		s.mu.Lock()
+		if s.progress == nil {
+			s.progress = make(map[int]int)
+		}
+		s.progress[ev.Endpoint] = ev.Message + 1
+		s.mu.Unlock()
+	case EventSent:
+		s.sent.Add(int64(ev.Bytes))
+	case EventReceived:
+		s.recv.Add(int64(ev.Bytes))
+	case EventError:
+		s.errors.Add(1)
+	}
+}
+
+func (s *runStats) latency(d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, d)
+	if len(s.latencies) > maxResponseSamples {
+		s.latencies = s.latencies[len(s.latencies)-maxResponseSamples:]
+	}
+}
+
+// Stats returns a snapshot of the run counters.
+func (e *Engine) Stats() RunStats {
+	st := RunStats{
+		Connections: e.stats.conns.Load(),
+		Messages:    e.stats.messages.Load(),
+		BytesSent:   e.stats.sent.Load(),
+		BytesRecv:   e.stats.recv.Load(),
+		Errors:      e.stats.errors.Load(),
+		Progress:    make(map[int]Progress),
+	}
+
+	e.stats.mu.Lock()
+	st.Latencies = slices.Clone(e.stats.latencies)
+	done := e.stats.progress
+	for id, msgs := range e.scenario().MessagesByFrom {
+		st.Progress[id] = Progress{Message: min(done[id], len(msgs)), Total: len(msgs)}
+	}
+	e.stats.mu.Unlock()
+
+	return st
+}
+
+// Percentile returns the p-th percentile (0-100) of samples, or zero when
+// there are none.
+func Percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	i := int(p / 100 * float64(len(sorted)-1))
+	return sorted[max(min(i, len(sorted)-1), 0)]
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// timeouts holds the effective network timeouts for one endpoint.
+// Zero read and idle timeouts disable them.
+type timeouts struct {
+	connect time.Duration
+	read    time.Duration
+	write   time.Duration
+	idle    time.Duration
+}
+
+// TimeoutError reports which timeout fired on which connection.
+type TimeoutError struct {
+	Op       string // "connect", "read", "write" or "idle"
+	Endpoint int
+	Peer     int // -1 when unknown (accepted connections)
+	Timeout  time.Duration
+	Err      error
+}
+
+func (t *TimeoutError) Error() string {
+	peer := "?"
+	if t.Peer >= 0 {
+		peer = fmt.Sprint(t.Peer)
+	}
+	return fmt.Sprintf("%s timeout (%v) on %d -> %s", t.Op, t.Timeout, t.Endpoint, peer)
+}
+
+func (t *TimeoutError) Unwrap() error { return t.Err }
+
+// timeoutsFor resolves the timeouts for ep, falling back to Globals and
+// finally to the engine defaults.
+func (e *Engine) timeoutsFor(ep *types.Endpoint) timeouts {
+	g := e.scenario().Globals
+	t := timeouts{
+		connect: e.timeout,
+		read:    ms(g.ReadTimeout),
+		write:   ms(g.WriteTimeout),
+		idle:    ms(g.IdleTimeout),
+	}
+
+	if ep != nil {
+		if ep.Timeout > 0 {
+			t.connect = ms(ep.Timeout)
+		}
+		if ep.ReadTimeout > 0 {
+			t.read = ms(ep.ReadTimeout)
+		}
+		if ep.WriteTimeout > 0 {
+			t.write = ms(ep.WriteTimeout)
+		}
+		if ep.IdleTimeout > 0 {
+			t.idle = ms(ep.IdleTimeout)
+		}
+	}
+
+	// Writes must never block forever on a stalled peer
+	if t.write <= 0 {
+		t.write = t.connect
+	}
+
+	return t
+}
+
+// finDrain is how long a fin waits for the peer to close its side.
+func (e *Engine) finDrain(ep *types.Endpoint) time.Duration {
+	if ep != nil && ep.FinDrain > 0 {
+		return ms(ep.FinDrain)
+	}
+	return ms(e.scenario().Globals.FinDrain)
+}
+
+func ms(v int) time.Duration {
+	if v <= 0 {
+		return 0
+	}
+	return time.Duration(v) * time.Millisecond
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// transcript records every byte moving over one connection, in order, in
+// the spirit of Wireshark's Follow TCP Stream.
+type transcript struct {
+	mu     sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	start  time.Time
+	closed bool
+}
+
+// EnableTranscripts writes a transcript file per connection into dir. It
+// must be called before any endpoint is started.
+func (e *Engine) EnableTranscripts(dir string) {
+	e.transcriptDir = dir
+}
+
+func (e *Engine) openTranscript(c net.Conn, id, peer int) (*transcript, error) {
+	if err := os.MkdirAll(e.transcriptDir, 0755); err != nil {
+		return nil, err
+	}
+
+	to := "accepted"
+	if peer >= 0 {
+		to = fmt.Sprintf("to%d", peer)
+	}
+	// Numbers taken by earlier runs are skipped rather than overwritten
+	var f *os.File
+	for {
+		seq := e.transcriptSeq.Add(1)
+		name := filepath.Join(e.transcriptDir, fmt.Sprintf("ep%d-%s-%03d.txt", id, to, seq))
+		var err error
+		f, err = os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+	}
+
+	t := &transcript{f: f, w: bufio.NewWriter(f), start: time.Now()}
+	fmt.Fprintf(t.w, "# endpoint %d", id)
+	if peer >= 0 {
+		fmt.Fprintf(t.w, " -> %d", peer)
+	}
+	fmt.Fprintf(t.w, "\n# local %s, remote %s\n# started %s\n",
+		c.LocalAddr(), c.RemoteAddr(), t.start.Format(time.RFC3339Nano))
+	return t, nil
+}
+
+// record appends a dump of data; out is true for sent bytes.
+func (t *transcript) record(out bool, data []byte) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+
+	dir, verb := "<<<", "received"
+	if out {
+		dir, verb = ">>>", "sent"
+	}
+	fmt.Fprintf(t.w, "\n%s +%.3fs %s %d bytes\n", dir, time.Since(t.start).Seconds(), verb, len(data))
+	t.w.WriteString(hex.Dump(data))
+}
+
+// note appends a connection event such as a half-close or reset.
+func (t *transcript) note(text string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	fmt.Fprintf(t.w, "\n--- +%.3fs %s\n", time.Since(t.start).Seconds(), text)
+}
+
+func (t *transcript) close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	fmt.Fprintf(t.w, "\n--- +%.3fs closed\n", time.Since(t.start).Seconds())
+	t.w.Flush()
+	t.f.Close()
+}
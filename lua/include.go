@@ -0,0 +1,343 @@
+package lua
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/yuin/gluamapper"
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// A scenario can pull in other scenario files with two top-level keys,
+// resolved relative to the file that names them:
+//
+//	config.extends = "base.lua"                -- inherit everything, override below
+//	config.include = { "topology.lua", ... }   -- add endpoints and messages
+//
+// Endpoints of a file override those it extends with the same ID, while an ID
+// defined by two includes, or by an include and the including file, is an
+// error. Messages of a file replace the ones it extends; included messages come
+// before the file's own. Globals only come from the file and what it extends.
+const (
+	includeKey = "include"
+	extendsKey = "extends"
+)
+
+// scenarioFile is one loaded file before its includes are resolved.
+type scenarioFile struct {
+	path     string
+	cfg      *types.Config
+	includes []string
+	extends  string
+}
+
+// loader resolves a scenario and the files it includes or extends.
+type loader struct {
+	ctx      context.Context
+	opts     Options
+	included map[string]bool // Files already merged through include
+	sources  []string        // Every file read, in order
+
+	declaredNames map[string]bool // Params declared by any file
+	paramsErr     *ParamsError    // Set when a file lacks required params
+	collect       bool            // Stop at declare_params, see DeclaredParams
+	collected     bool
+	declared      []Param
+}
+
+func newLoader(ctx context.Context, opts Options) *loader {
+	return &loader{
+		ctx:           ctx,
+		opts:          opts,
+		included:      make(map[string]bool),
+		declaredNames: make(map[string]bool),
+	}
+}
+
+func (ld *loader) addSource(path string) {
+	if !slices.Contains(ld.sources, path) {
+		ld.sources = append(ld.sources, path)
+	}
+}
+
+func (ld *loader) load(path string, chain []string) (*types.Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if i := slices.Index(chain, abs); i >= 0 {
+		cycle := append(slices.Clone(chain[i:]), abs)
+		return nil, fmt.Errorf("%s: include cycle: %s", path, strings.Join(cycle, " -> "))
+	}
+	chain = append(chain, abs)
+
+	ld.addSource(path)
+	f, err := ld.loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &types.Config{Globals: f.cfg.Globals}
+	owner := make(map[int]string)   // Endpoint ID -> file defining it
+	inherited := make(map[int]bool) // IDs from the extended file, open to override
+	index := make(map[int]int)      // Endpoint ID -> position in out.Endpoints
+	add := func(ep types.Endpoint, from string) error {
+		other, dup := owner[ep.ID]
+		switch {
+		case !dup || other == from:
+			// Duplicates within one file are left to ValidateConfig
+			index[ep.ID] = len(out.Endpoints)
+			out.Endpoints = append(out.Endpoints, ep)
+		case inherited[ep.ID] && from == path:
+			out.Endpoints[index[ep.ID]] = ep
+		default:
+			return fmt.Errorf("%s: endpoint %d is also defined in %s", from, ep.ID, other)
+		}
+		owner[ep.ID] = from
+		inherited[ep.ID] = false
+		return nil
+	}
+
+	if f.extends != "" {
+		basePath := f.resolve(f.extends)
+		base, err := ld.load(basePath, chain)
+		if err != nil {
+			return nil, err
+		}
+		out.Globals = mergeGlobals(base.Globals, f.cfg.Globals)
+		for _, ep := range base.Endpoints {
+			index[ep.ID] = len(out.Endpoints)
+			out.Endpoints = append(out.Endpoints, ep)
+			owner[ep.ID] = basePath
+			inherited[ep.ID] = true
+		}
+		if len(f.cfg.Messages) == 0 {
+			out.Messages = base.Messages
+		}
+	}
+
+	for _, name := range f.includes {
+		incPath := f.resolve(name)
+		key, err := filepath.Abs(incPath)
+		if err != nil {
+			return nil, err
+		}
+		// Shared files reached through several includes count once
+		if ld.included[key] {
+			continue
+		}
+		ld.included[key] = true
+
+		part, err := ld.load(incPath, chain)
+		if err != nil {
+			return nil, err
+		}
+		for _, ep := range part.Endpoints {
+			if err := add(ep, incPath); err != nil {
+				return nil, err
+			}
+		}
+		out.Messages = append(out.Messages, part.Messages...)
+	}
+
+	for _, ep := range f.cfg.Endpoints {
+		if err := add(ep, path); err != nil {
+			return nil, err
+		}
+	}
+	out.Messages = append(out.Messages, f.cfg.Messages...)
+
+	return out, nil
+}
+
+// loadFile runs one scenario file and maps its table, without following
+// includes.
+func (ld *loader) loadFile(path string) (*scenarioFile, error) {
+	L := newState(ld.opts)
+	defer L.Close()
+	ld.setupRequire(L, filepath.Dir(path))
+	ld.setupParams(L, path)
+
+	// Execute Lua file
+	if err := run(ld.ctx, L, path, ld.opts); err != nil {
+		if ld.paramsErr != nil {
+			return nil, ld.paramsErr
+		}
+		return nil, err
+	}
+
+	// Lua file returns config table
+	table, ok := L.Get(-1).(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("%s: lua file did not return a table", path)
+	}
+
+	f := &scenarioFile{path: path, cfg: &types.Config{}}
+	var err error
+	if f.includes, err = stringList(table.RawGetString(includeKey)); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", path, includeKey, err)
+	}
+	switch v := table.RawGetString(extendsKey).(type) {
+	case *lua.LNilType:
+	case lua.LString:
+		f.extends = string(v)
+	default:
+		return nil, fmt.Errorf("%s: %s must be a file name", path, extendsKey)
+	}
+
+	// Map Lua table → Go struct, keys named by the lua struct tags
+	mapper := gluamapper.NewMapper(gluamapper.Option{NameFunc: gluamapper.Id, TagName: tagName})
+	if err := mapper.Map(table, f.cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// resolve returns name relative to the directory of the file.
+func (f *scenarioFile) resolve(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(filepath.Dir(f.path), name)
+}
+
+// stringList accepts nil, a string or a list of strings.
+func stringList(v lua.LValue) ([]string, error) {
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LString:
+		return []string{string(v)}, nil
+	case *lua.LTable:
+		var out []string
+		for i := 1; i <= v.Len(); i++ {
+			s, ok := v.RawGetInt(i).(lua.LString)
+			if !ok {
+				return nil, fmt.Errorf("entry %d is not a file name", i)
+			}
+			out = append(out, string(s))
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("want a file name or a list of file names")
+}
+
+// mergeGlobals returns base with every non-zero field of override applied.
+func mergeGlobals(base, override types.Globals) types.Globals {
+	b := reflect.ValueOf(&base).Elem()
+	o := reflect.ValueOf(override)
+	for i := 0; i < o.NumField(); i++ {
+		if !o.Field(i).IsZero() {
+			b.Field(i).Set(o.Field(i))
+		}
+	}
+	return base
+}
+
+// setupRequire makes require find modules next to the scenario. Sandboxed
+// states get a require that only loads .lua files below dir; others get the
+// package library with dir in front of package.path. Either way the modules
+// loaded are recorded as sources of the scenario.
+func (ld *loader) setupRequire(L *lua.LState, dir string) {
+	if !ld.opts.Sandbox {
+		pkg, ok := L.GetGlobal(lua.LoadLibName).(*lua.LTable)
+		if !ok {
+			return
+		}
+		path := filepath.Join(dir, "?.lua") + ";" + filepath.Join(dir, "?", "init.lua")
+		if old := lua.LVAsString(pkg.RawGetString("path")); old != "" {
+			path += ";" + old
+		}
+		pkg.RawSetString("path", lua.LString(path))
+
+		// Replace the package.path searcher with one that records its files
+		if loaders, ok := pkg.RawGetString("loaders").(*lua.LTable); ok {
+			loaders.RawSetInt(2, L.NewFunction(func(L *lua.LState) int {
+				file, msg := searchPath(L.CheckString(1), lua.LVAsString(pkg.RawGetString("path")))
+				if file == "" {
+					L.Push(lua.LString(msg))
+					return 1
+				}
+				ld.addSource(file)
+				fn, err := L.LoadFile(file)
+				if err != nil {
+					L.RaiseError("%v", err)
+				}
+				L.Push(fn)
+				return 1
+			}))
+		}
+		return
+	}
+
+	loaded := make(map[string]lua.LValue)
+	L.SetGlobal("require", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		if v, ok := loaded[name]; ok {
+			L.Push(v)
+			return 1
+		}
+
+		file, err := findModule(dir, name)
+		if err != nil {
+			L.RaiseError("%v", err)
+		}
+		ld.addSource(file)
+		fn, err := L.LoadFile(file)
+		if err != nil {
+			L.RaiseError("%v", err)
+		}
+		L.Push(fn)
+		L.Push(lua.LString(name))
+		L.Call(1, 1)
+
+		v := L.Get(-1)
+		if v == lua.LNil {
+			v = lua.LTrue
+		}
+		loaded[name] = v
+		L.Push(v)
+		return 1
+	}))
+}
+
+// searchPath looks for module name in the ;-separated templates of path,
+// like the searcher of the package library, and returns the first file that
+// exists or why none did.
+func searchPath(name, path string) (string, string) {
+	name = strings.ReplaceAll(name, ".", string(os.PathSeparator))
+	var tried []string
+	for _, pattern := range strings.Split(path, ";") {
+		file := strings.ReplaceAll(pattern, "?", name)
+		if _, err := os.Stat(file); err != nil {
+			tried = append(tried, err.Error())
+			continue
+		}
+		return file, ""
+	}
+	return "", strings.Join(tried, "\n\t")
+}
+
+// findModule maps a module name like "lib.payloads" to lib/payloads.lua or
+// lib/payloads/init.lua below dir.
+func findModule(dir, name string) (string, error) {
+	rel := filepath.FromSlash(strings.ReplaceAll(name, ".", "/"))
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("module %q: name must stay inside the scenario directory", name)
+	}
+	for _, candidate := range []string{rel + ".lua", filepath.Join(rel, "init.lua")} {
+		p := filepath.Join(dir, candidate)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("module %q not found in %s", name, dir)
+}
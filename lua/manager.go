@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/samaelod/nabu/config"
 	"github.com/samaelod/nabu/types"
@@ -59,8 +60,8 @@ func SaveToRecent(cfg *types.Config, originalPath string) (string, error) {
 	}
 	defer f.Close()
 
-	if strings.HasSuffix(originalPath, ".lua") {
-		// If original is Lua, copy it directly to preserve comments/structure
+	if strings.HasSuffix(originalPath, ".lua") && len(cfg.Sources) <= 1 {
+		// If original is a single Lua file, copy it directly to preserve comments/structure
 		src, err := os.Open(originalPath)
 		if err != nil {
 			return "", fmt.Errorf("failed to open source lua file: %w", err)
@@ -71,11 +72,22 @@ func SaveToRecent(cfg *types.Config, originalPath string) (string, error) {
 			return "", fmt.Errorf("failed to copy lua content: %w", err)
 		}
 	} else {
-		// If PCAP (or other), generate Lua from Config struct
+		// If PCAP (or other), or Lua spread over several files, generate
+		// Lua from Config struct
 		if err := WriteConfig(f, cfg); err != nil {
 			return "", fmt.Errorf("failed to write config to lua: %w", err)
 		}
 	}
 
+	// The scenario is usable without its index entry, so failing to
+	// record it is not an error
+	source, err := filepath.Abs(originalPath)
+	if err != nil {
+		source = originalPath
+	}
+	UpdateRecent(recentDir, filepath.Base(newPath), func(e *RecentEntry) {
+		*e = RecentEntry{Source: source, Imported: time.Now(), Endpoints: len(cfg.Endpoints), Messages: len(cfg.Messages)}
+	})
+
 	return newPath, nil
 }
@@ -0,0 +1,219 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Scenarios declare their parameters with
+//
+//	local p = declare_params {
+//	    host  = "127.0.0.1",                                  -- default, type inferred
+//	    users = { type = "integer", default = 10 },
+//	    token = { type = "string", required = true, description = "API token" },
+//	}
+//
+// Values come from Options.Params (-p name=value or the TUI prompt), then the
+// NABU_PARAM_<NAME> environment variable, then the default. The returned
+// table is also the global params, which holds the raw Options.Params before
+// declare_params is called.
+const (
+	paramsGlobal  = "params"
+	declareParams = "declare_params"
+	// ParamEnvPrefix prefixes the environment variable of a parameter.
+	ParamEnvPrefix = "NABU_PARAM_"
+)
+
+var paramTypes = map[string]bool{"string": true, "number": true, "integer": true, "boolean": true}
+
+// Param is a parameter declared by a scenario.
+type Param struct {
+	Name        string
+	Type        string // string, number, integer or boolean
+	Default     string // Empty when there is none
+	Required    bool
+	Description string
+	Value       string // Value the scenario would get, empty when unset
+}
+
+// ParamsError reports required parameters that were given no value.
+type ParamsError struct {
+	File    string
+	Missing []string
+	Params  []Param // Everything the file declares
+}
+
+func (e *ParamsError) Error() string {
+	return fmt.Sprintf("%s: missing required params %s (set with -p name=value or %s<NAME>)",
+		e.File, strings.Join(e.Missing, ", "), ParamEnvPrefix)
+}
+
+// errDeclared stops a script once its params are declared, see DeclaredParams.
+var errDeclared = errors.New("params declared")
+
+// DeclaredParams runs the scenario at path up to its declare_params call and
+// returns the declared parameters with the values opts would give them. It
+// returns nil if the scenario declares none.
+func DeclaredParams(path string, opts Options) ([]Param, error) {
+	ctx, stop := limit(context.Background(), opts)
+	defer stop()
+
+	ld := newLoader(ctx, opts)
+	ld.collect = true
+	if _, err := ld.loadFile(path); err != nil && !ld.collected {
+		return nil, err
+	}
+	return ld.declared, nil
+}
+
+// setupParams installs params and declare_params in L for the file at path.
+func (ld *loader) setupParams(L *lua.LState, path string) {
+	raw := L.NewTable()
+	for name, v := range ld.opts.Params {
+		raw.RawSetString(name, lua.LString(v))
+	}
+	L.SetGlobal(paramsGlobal, raw)
+
+	L.SetGlobal(declareParams, L.NewFunction(func(L *lua.LState) int {
+		specs := L.CheckTable(1)
+
+		var params []Param
+		var missing []string
+		values := make(map[string]lua.LValue)
+		var failed error
+		specs.ForEach(func(k, v lua.LValue) {
+			if failed != nil {
+				return
+			}
+			name, ok := k.(lua.LString)
+			if !ok {
+				failed = fmt.Errorf("param names must be strings, got %s", k.Type())
+				return
+			}
+			p, def, err := parseParam(string(name), v)
+			if err != nil {
+				failed = err
+				return
+			}
+
+			raw, ok := ld.opts.Params[p.Name]
+			if !ok {
+				raw, ok = os.LookupEnv(ParamEnvPrefix + strings.ToUpper(p.Name))
+			}
+			switch {
+			case ok:
+				val, err := convertParam(p, raw)
+				if err != nil {
+					failed = err
+					return
+				}
+				p.Value = raw
+				values[p.Name] = val
+			case def != lua.LNil:
+				p.Value = p.Default
+				values[p.Name] = def
+			case p.Required:
+				missing = append(missing, p.Name)
+			}
+			params = append(params, p)
+			ld.declaredNames[p.Name] = true
+		})
+		if failed != nil {
+			L.RaiseError("%s: %v", declareParams, failed)
+		}
+		sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+		sort.Strings(missing)
+
+		if ld.collect {
+			ld.declared = params
+			ld.collected = true
+			L.RaiseError("%v", errDeclared)
+		}
+		if len(missing) > 0 {
+			ld.paramsErr = &ParamsError{File: path, Missing: missing, Params: params}
+			L.RaiseError("%v", ld.paramsErr)
+		}
+
+		out := L.NewTable()
+		for name, v := range values {
+			out.RawSetString(name, v)
+		}
+		L.SetGlobal(paramsGlobal, out)
+		L.Push(out)
+		return 1
+	}))
+}
+
+// parseParam reads one declaration: a table with type, default, required and
+// description, or a bare default value.
+func parseParam(name string, v lua.LValue) (Param, lua.LValue, error) {
+	p := Param{Name: name}
+	def := v
+	if t, ok := v.(*lua.LTable); ok {
+		def = t.RawGetString("default")
+		p.Type = lua.LVAsString(t.RawGetString("type"))
+		p.Required = lua.LVAsBool(t.RawGetString("required"))
+		p.Description = lua.LVAsString(t.RawGetString("description"))
+	}
+
+	if p.Type == "" {
+		switch def.(type) {
+		case lua.LNumber:
+			p.Type = "number"
+		case lua.LBool:
+			p.Type = "boolean"
+		default:
+			p.Type = "string"
+		}
+	}
+	if !paramTypes[p.Type] {
+		return p, nil, fmt.Errorf("param %s: unknown type %q (want string, number, integer or boolean)", name, p.Type)
+	}
+
+	if def != lua.LNil {
+		p.Default = def.String()
+		var err error
+		if def, err = convertParam(p, p.Default); err != nil {
+			return p, nil, fmt.Errorf("default of %w", err)
+		}
+	}
+	return p, def, nil
+}
+
+// convertParam parses raw as the type of p.
+func convertParam(p Param, raw string) (lua.LValue, error) {
+	switch p.Type {
+	case "number":
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return lua.LNumber(f), nil
+		}
+	case "integer":
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return lua.LNumber(n), nil
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return lua.LBool(b), nil
+		}
+	default:
+		return lua.LString(raw), nil
+	}
+	return nil, fmt.Errorf("param %s: %q is not a valid %s", p.Name, raw, p.Type)
+}
+
+// ParseParam splits a "name=value" command-line parameter.
+func ParseParam(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid param %q (want name=value)", s)
+	}
+	return name, value, nil
+}
@@ -1,63 +1,83 @@
 package lua
 
 import (
+	"context"
 	"fmt"
-
-	"github.com/yuin/gluamapper"
-	lua "github.com/yuin/gopher-lua"
+	"sort"
+	"strings"
 
 	"github.com/samaelod/nabu/types"
 )
 
+// ReadLuaConfig loads and validates the scenario at path with the script
+// options of the app config.
 func ReadLuaConfig(path string) (*types.Config, error) {
-	L := lua.NewState()
-	defer L.Close()
-
-	// Execute Lua file
-	if err := L.DoFile(path); err != nil {
-		return nil, err
-	}
-
-	// Lua file returns config table
-	lv := L.Get(-1)
-	table, ok := lv.(*lua.LTable)
-	if !ok {
-		return nil, fmt.Errorf("lua file did not return a table")
-	}
-
-	var cfg types.Config
+	return ReadLuaConfigWithOptions(context.Background(), path, defaultOptions())
+}
 
-	// Map Lua table → Go struct
-	if err := gluamapper.Map(table, &cfg); err != nil {
+// ReadLuaConfigWithOptions is ReadLuaConfig with explicit script options;
+// cancelling ctx aborts the script.
+func ReadLuaConfigWithOptions(ctx context.Context, path string, opts Options) (*types.Config, error) {
+	cfg, err := readLuaConfig(ctx, path, opts)
+	if err != nil {
 		return nil, err
 	}
 
 	// Validate the config
-	if err := ValidateConfig(&cfg); err != nil {
+	if err := ValidateConfig(cfg); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
 	// Index messages for O(1) lookup
 	cfg.IndexMessages()
 
-	return &cfg, nil
+	return cfg, nil
 }
 
-func ValidateConfig(cfg *types.Config) error {
-	endpoints := make(map[int]bool)
+// LintFile loads the scenario or capture at path without validating it,
+// remaps it like LoadScenario, and returns every problem Lint finds.
+func LintFile(path string) ([]Problem, error) {
+	return LintFileWithOptions(context.Background(), path, defaultOptions())
+}
 
-	for _, ep := range cfg.Endpoints {
-		endpoints[ep.ID] = true
+// LintFileWithOptions is LintFile with explicit script options.
+func LintFileWithOptions(ctx context.Context, path string, opts Options) ([]Problem, error) {
+	cfg, err := ReadScenario(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := remapScenario(cfg, opts); err != nil {
+		return nil, err
+	}
+	return Lint(cfg), nil
+}
+
+// readLuaConfig loads the scenario at path and the files it includes or
+// extends, all under one set of limits.
+func readLuaConfig(ctx context.Context, path string, opts Options) (*types.Config, error) {
+	ctx, stop := limit(ctx, opts)
+	defer stop()
+
+	ld := newLoader(ctx, opts)
+	cfg, err := ld.load(path, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	for i, msg := range cfg.Messages {
-		if !endpoints[msg.From] {
-			return fmt.Errorf("message %d: invalid from id %d", i, msg.From)
+	// Catch misspelled params once every file had a chance to declare them
+	if len(ld.declaredNames) > 0 {
+		var unknown []string
+		for name := range opts.Params {
+			if !ld.declaredNames[name] {
+				unknown = append(unknown, name)
+			}
 		}
-		if !endpoints[msg.To] {
-			return fmt.Errorf("message %d: invalid to id %d", i, msg.To)
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("%s: unknown params %s", path, strings.Join(unknown, ", "))
 		}
 	}
 
-	return nil
+	cfg.Sources = ld.sources
+	return cfg, nil
 }
@@ -0,0 +1,212 @@
+package lua
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samaelod/nabu/config"
+)
+
+// recentIndex is the file in the recent directory holding what is known
+// about the scenarios saved there, by file name.
+const recentIndex = "index.json"
+
+// recentMu serializes updates of the index.
+var recentMu sync.Mutex
+
+// RecentEntry describes a scenario saved in the recent directory.
+type RecentEntry struct {
+	Name      string     `json:"-"`      // File name in the recent directory
+	Source    string     `json:"source"` // File it was imported from, empty if unknown
+	Imported  time.Time  `json:"imported"`
+	Endpoints int        `json:"endpoints"` // Zero if unknown, like Messages
+	Messages  int        `json:"messages"`
+	Pinned    bool       `json:"pinned,omitempty"`
+	LastRun   *RunResult `json:"last_run,omitempty"`
+}
+
+// RunResult is how the last run of a scenario ended.
+type RunResult struct {
+	Time      time.Time `json:"time"`
+	Completed int       `json:"completed"` // Endpoints that finished their messages
+	Errors    int       `json:"errors"`    // Endpoints that stopped on an error
+}
+
+// RecentDir returns the recent directory of the app config.
+func RecentDir() string {
+	appConfig, err := config.LoadDefault()
+	if err != nil || appConfig.RecentDir == "" {
+		return "recent"
+	}
+	return appConfig.RecentDir
+}
+
+// ListRecent returns the scenarios in dir, pinned ones first and then the
+// most recently imported. A missing directory has none.
+func ListRecent(dir string) ([]RecentEntry, error) {
+	recentMu.Lock()
+	index, err := readRecentIndex(dir)
+	recentMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []RecentEntry
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".lua" {
+			continue
+		}
+		// Files saved before the index existed, or copied in by hand, are
+		// dated by their modification time
+		entry := index[f.Name()]
+		if entry.Imported.IsZero() {
+			info, err := f.Info()
+			if err != nil {
+				continue
+			}
+			entry.Imported = info.ModTime()
+		}
+		entry.Name = f.Name()
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Pinned != entries[j].Pinned {
+			return entries[i].Pinned
+		}
+		return entries[i].Imported.After(entries[j].Imported)
+	})
+	return entries, nil
+}
+
+// UpdateRecent applies update to the entry of the scenario name in dir,
+// adding the entry, dated by the file's modification time, if the index has
+// none.
+func UpdateRecent(dir, name string, update func(*RecentEntry)) error {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+
+	index, err := readRecentIndex(dir)
+	if err != nil {
+		return err
+	}
+	entry, ok := index[name]
+	if !ok {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			entry.Imported = info.ModTime()
+		}
+	}
+	update(&entry)
+	index[name] = entry
+	return writeRecentIndex(dir, index)
+}
+
+// RenameRecent renames the scenario name in dir to newName, adding the .lua
+// extension when it is missing, and keeps its entry.
+func RenameRecent(dir, name, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if filepath.Ext(newName) != ".lua" {
+		newName += ".lua"
+	}
+	if newName == ".lua" || filepath.Base(newName) != newName || newName == recentIndex {
+		return fmt.Errorf("invalid name %q", newName)
+	}
+	if newName == name {
+		return nil
+	}
+
+	recentMu.Lock()
+	defer recentMu.Unlock()
+
+	newPath := filepath.Join(dir, newName)
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("%s already exists", newName)
+	}
+	if err := os.Rename(filepath.Join(dir, name), newPath); err != nil {
+		return err
+	}
+
+	index, err := readRecentIndex(dir)
+	if err != nil {
+		return err
+	}
+	if entry, ok := index[name]; ok {
+		delete(index, name)
+		index[newName] = entry
+	}
+	return writeRecentIndex(dir, index)
+}
+
+// DeleteRecent removes the scenario name from dir and from its index.
+func DeleteRecent(dir, name string) error {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+
+	if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	index, err := readRecentIndex(dir)
+	if err != nil {
+		return err
+	}
+	delete(index, name)
+	return writeRecentIndex(dir, index)
+}
+
+func readRecentIndex(dir string) (map[string]RecentEntry, error) {
+	index := make(map[string]RecentEntry)
+	data, err := os.ReadFile(filepath.Join(dir, recentIndex))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("%s: %w", recentIndex, err)
+	}
+	return index, nil
+}
+
+// writeRecentIndex replaces the index once it has been written completely,
+// dropping entries of files that no longer exist.
+func writeRecentIndex(dir string, index map[string]RecentEntry) error {
+	for name := range index {
+		if _, err := os.Stat(filepath.Join(dir, name)); errors.Is(err, os.ErrNotExist) {
+			delete(index, name)
+		}
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".index-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, recentIndex))
+}
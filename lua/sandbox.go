@@ -0,0 +1,205 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/samaelod/nabu/config"
+)
+
+const (
+	// sandboxCallStack bounds nested Lua calls in sandboxed scripts.
+	sandboxCallStack = 200
+	// sandboxRegistryMax bounds the Lua value stack (slots) of sandboxed scripts.
+	sandboxRegistryMax = 1 << 20
+	// memoryPoll is how often the heap is sampled while a script runs.
+	memoryPoll = 10 * time.Millisecond
+)
+
+// Options controls how scenario scripts are executed.
+type Options struct {
+	Sandbox  bool              // Open only safe libraries and limit the call stack
+	Timeout  time.Duration     // Abort scripts running longer than this (0 = no limit)
+	MemoryMB int               // Abort scripts growing the heap by more than this (0 = no limit)
+	Params   map[string]string // Parameter values, see declare_params
+	Remap    []string          // Remap rules tried before the scenario's own, see LoadScenario
+}
+
+// OptionsFor returns the script options configured in the app config.
+func OptionsFor(appConfig *config.Config) Options {
+	return Options{
+		Sandbox:  appConfig.LuaSandbox,
+		Timeout:  time.Duration(appConfig.LuaTimeoutMs) * time.Millisecond,
+		MemoryMB: appConfig.LuaMemoryMB,
+		Remap:    appConfig.Remap,
+	}
+}
+
+func defaultOptions() Options {
+	// The defaults come with the error
+	appConfig, _ := config.LoadDefault()
+	return OptionsFor(appConfig)
+}
+
+var errMemoryLimit = errors.New("memory limit exceeded")
+
+// ScriptError is an error raised while loading or running a scenario script.
+type ScriptError struct {
+	File  string
+	Line  int // 0 when unknown
+	Msg   string
+	Stack string // Lua traceback, empty for syntax errors
+}
+
+func (e *ScriptError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Msg)
+}
+
+var (
+	// "file:12: message" for runtime errors
+	runtimeErrPos = regexp.MustCompile(`^(.+?):(\d+): (?s)(.*)$`)
+	// "file line:12(column:5) near 'x': message" for syntax errors
+	syntaxErrPos = regexp.MustCompile(`^(.+?) line:(\d+)\(column:\d+\) (?s)(.*)$`)
+)
+
+// newState creates a Lua state for opts. Sandboxed states get the base,
+// table, string, math and coroutine libraries plus os.time, os.clock and
+// os.date, and nothing that reaches the file system or other processes.
+func newState(opts Options) *lua.LState {
+	if !opts.Sandbox {
+		return lua.NewState()
+	}
+
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   sandboxCallStack,
+		RegistryMaxSize: sandboxRegistryMax,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+		{lua.CoroutineLibName, lua.OpenCoroutine},
+		{lua.OsLibName, lua.OpenOs},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	// Base functions that read files
+	for _, name := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	// Keep only the side-effect free parts of os
+	safeOs := L.NewTable()
+	if osLib, ok := L.GetGlobal(lua.OsLibName).(*lua.LTable); ok {
+		for _, name := range []string{"time", "clock", "date", "difftime"} {
+			safeOs.RawSetString(name, osLib.RawGetString(name))
+		}
+	}
+	L.SetGlobal(lua.OsLibName, safeOs)
+
+	return L
+}
+
+// limit derives a context from ctx that is cancelled once the time or memory
+// limits of opts are exceeded. stop releases it.
+func limit(ctx context.Context, opts Options) (_ context.Context, stop func()) {
+	var cancelTimeout context.CancelFunc = func() {}
+	if opts.Timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, opts.Timeout)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	done := make(chan struct{})
+	if opts.MemoryMB > 0 {
+		go watchMemory(ctx, done, cancel, uint64(opts.MemoryMB)<<20)
+	}
+
+	return ctx, func() {
+		close(done)
+		cancel(nil)
+		cancelTimeout()
+	}
+}
+
+// run executes the file at path in L under a context from limit and
+// converts Lua errors to *ScriptError.
+func run(ctx context.Context, L *lua.LState, path string, opts Options) error {
+	L.SetContext(ctx)
+	err := L.DoFile(path)
+	L.RemoveContext()
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *lua.ApiError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	se := &ScriptError{File: path, Msg: strings.TrimSpace(apiErr.Object.String()), Stack: apiErr.StackTrace}
+	for _, re := range []*regexp.Regexp{runtimeErrPos, syntaxErrPos} {
+		if m := re.FindStringSubmatch(se.Msg); m != nil {
+			se.File = m[1]
+			se.Line, _ = strconv.Atoi(m[2])
+			se.Msg = strings.TrimSpace(m[3])
+			break
+		}
+	}
+
+	// Name the limit instead of the context error gopher-lua reports
+	switch {
+	case context.Cause(ctx) == errMemoryLimit:
+		se.Msg = fmt.Sprintf("script exceeded the memory limit of %d MB", opts.MemoryMB)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		se.Msg = fmt.Sprintf("script exceeded the time limit of %v", opts.Timeout)
+	}
+	return se
+}
+
+// watchMemory cancels ctx once the heap has grown by more than limit bytes
+// since it started. The heap is shared with the rest of the process, so this
+// is a guard against runaway scripts rather than an exact quota.
+func watchMemory(ctx context.Context, done <-chan struct{}, cancel context.CancelCauseFunc, limit uint64) {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	heap := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+
+	start := heap()
+	ticker := time.NewTicker(memoryPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if now := heap(); now > start && now-start > limit {
+				cancel(errMemoryLimit)
+				return
+			}
+		}
+	}
+}
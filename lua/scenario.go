@@ -0,0 +1,61 @@
+package lua
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samaelod/nabu/pcapreader"
+	"github.com/samaelod/nabu/remap"
+	"github.com/samaelod/nabu/types"
+)
+
+// LoadScenario reads the capture or Lua scenario at path, remaps its
+// endpoints and validates the result. The TUI, nabu export and nabu lint
+// all load scenarios this way, so they agree on the addresses it runs with.
+func LoadScenario(ctx context.Context, path string, opts Options) (*types.Config, error) {
+	cfg, err := ReadScenario(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := PrepareScenario(cfg, opts); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ReadScenario reads the capture or Lua scenario at path as written, without
+// remapping or validating it.
+func ReadScenario(ctx context.Context, path string, opts Options) (*types.Config, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pcap", ".pcapng", ".cap":
+		return pcapreader.ReadPCAP(path)
+	}
+	return readLuaConfig(ctx, path, opts)
+}
+
+// PrepareScenario does what LoadScenario does after reading: it rewrites the
+// endpoints of cfg with opts.Remap followed by the scenario's own rules,
+// validates the result and indexes its messages.
+func PrepareScenario(cfg *types.Config, opts Options) error {
+	if err := remapScenario(cfg, opts); err != nil {
+		return err
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.IndexMessages()
+	return nil
+}
+
+// remapScenario applies opts.Remap, then the rules of cfg itself.
+func remapScenario(cfg *types.Config, opts Options) error {
+	rules, err := remap.ParseAll(slices.Concat(opts.Remap, cfg.Globals.Remap))
+	if err != nil {
+		return err
+	}
+	remap.Apply(cfg, rules)
+	return nil
+}
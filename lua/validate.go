@@ -0,0 +1,193 @@
+package lua
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// Severity tells whether a problem stops a scenario from loading.
+type Severity int
+
+const (
+	SeverityError   Severity = iota // The scenario is rejected
+	SeverityWarning                 // The scenario runs, but probably not as intended
+)
+
+// Problem is one issue found in a scenario.
+type Problem struct {
+	Severity Severity
+	Message  int // Index into Messages, -1 when not about a message
+	Endpoint int // Endpoint ID, -1 when not about an endpoint
+	Text     string
+}
+
+func (p Problem) String() string {
+	var prefix string
+	if p.Severity == SeverityWarning {
+		prefix = "warning: "
+	}
+	switch {
+	case p.Message >= 0:
+		return fmt.Sprintf("%smessage %d: %s", prefix, p.Message, p.Text)
+	case p.Endpoint >= 0:
+		return fmt.Sprintf("%sendpoint %d: %s", prefix, p.Endpoint, p.Text)
+	}
+	return fmt.Sprintf("%sglobals: %s", prefix, p.Text)
+}
+
+// ValidationError lists every problem that makes a scenario invalid.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].String()
+	}
+	parts := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		parts[i] = p.String()
+	}
+	return fmt.Sprintf("%d problems: %s", len(e.Problems), strings.Join(parts, "; "))
+}
+
+var (
+	endpointKinds = map[string]bool{"client": true, "server": true}
+	messageKinds  = map[string]bool{
+		"syn": true, "syn-ack": true, "ack": true,
+		"data": true, "psh": true, "push": true,
+		"fin": true, "rst": true,
+	}
+)
+
+// ValidateConfig returns a *ValidationError listing every error Lint finds,
+// or nil when the scenario can run. Warnings are not reported.
+func ValidateConfig(cfg *types.Config) error {
+	var errs []Problem
+	for _, p := range Lint(cfg) {
+		if p.Severity == SeverityError {
+			errs = append(errs, p)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: errs}
+}
+
+// Lint checks cfg and returns all problems in order: globals, endpoints,
+// then messages.
+func Lint(cfg *types.Config) []Problem {
+	var problems []Problem
+	add := func(sev Severity, msg, ep int, format string, args ...any) {
+		problems = append(problems, Problem{Severity: sev, Message: msg, Endpoint: ep, Text: fmt.Sprintf(format, args...)})
+	}
+
+	g := cfg.Globals
+	if g.Protocol != "" && g.Protocol != "tcp" {
+		add(SeverityError, -1, -1, "unsupported protocol %q (only \"tcp\")", g.Protocol)
+	}
+	if g.PlayMode != "" && g.PlayMode != "pcap" {
+		add(SeverityError, -1, -1, "unsupported play_mode %q (only \"pcap\")", g.PlayMode)
+	}
+
+	endpoints := make(map[int]types.Endpoint, len(cfg.Endpoints))
+	listeners := make(map[string]int)
+	for _, ep := range cfg.Endpoints {
+		if _, dup := endpoints[ep.ID]; dup {
+			add(SeverityError, -1, ep.ID, "duplicate endpoint id")
+			continue
+		}
+		endpoints[ep.ID] = ep
+
+		if !endpointKinds[ep.Kind] {
+			add(SeverityError, -1, ep.ID, "unknown kind %q (want \"client\" or \"server\")", ep.Kind)
+		}
+		if ep.Port < 0 || ep.Port > 65535 {
+			add(SeverityError, -1, ep.ID, "invalid port %d", ep.Port)
+		} else if ep.Kind == "server" && ep.Port == 0 {
+			add(SeverityError, -1, ep.ID, "server needs a port")
+		}
+
+		if ep.Kind == "server" {
+			addr := net.JoinHostPort(ep.Address, strconv.Itoa(ep.Port))
+			if other, dup := listeners[addr]; dup {
+				add(SeverityError, -1, ep.ID, "listens on %s like endpoint %d", addr, other)
+			} else {
+				listeners[addr] = ep.ID
+			}
+		}
+	}
+
+	for _, id := range g.Load.Endpoints {
+		if ep, ok := endpoints[id]; !ok {
+			add(SeverityError, -1, -1, "load profile endpoint %d not found", id)
+		} else if ep.Kind == "server" {
+			add(SeverityError, -1, -1, "load profile endpoint %d is a server", id)
+		}
+	}
+	for i, st := range g.Load.Stages {
+		if st.Duration < 0 || st.Rate < 0 {
+			add(SeverityError, -1, -1, "load stage %d: negative duration or rate", i)
+		}
+		if st.Shape != "" && st.Shape != "ramp" && st.Shape != "step" {
+			add(SeverityError, -1, -1, "load stage %d: unknown shape %q", i, st.Shape)
+		}
+	}
+
+	// Connections opened by a syn, keyed by the unordered endpoint pair
+	open := make(map[[2]int]bool)
+	pair := func(a, b int) [2]int {
+		if a > b {
+			a, b = b, a
+		}
+		return [2]int{a, b}
+	}
+
+	for i, msg := range cfg.Messages {
+		from, fromOK := endpoints[msg.From]
+		to, toOK := endpoints[msg.To]
+		if !fromOK {
+			add(SeverityError, i, -1, "invalid from id %d", msg.From)
+		}
+		if !toOK {
+			add(SeverityError, i, -1, "invalid to id %d", msg.To)
+		}
+		if msg.From == msg.To {
+			add(SeverityError, i, -1, "from and to are both endpoint %d", msg.From)
+		}
+		if fromOK && toOK && from.Kind == "client" && to.Kind == "client" && msg.From != msg.To {
+			add(SeverityError, i, -1, "between two clients (%d -> %d)", msg.From, msg.To)
+		}
+
+		if !messageKinds[msg.Kind] {
+			add(SeverityError, i, -1, "unknown kind %q", msg.Kind)
+		}
+		if _, err := hex.DecodeString(msg.Value); err != nil {
+			add(SeverityError, i, -1, "value is not hex: %v", err)
+		}
+		if msg.TDelta < 0 {
+			add(SeverityError, i, -1, "negative t_delta %d", msg.TDelta)
+		}
+
+		p := pair(msg.From, msg.To)
+		switch msg.Kind {
+		case "syn":
+			open[p] = true
+		case "data", "psh", "push", "fin", "rst":
+			if !open[p] {
+				add(SeverityWarning, i, -1, "%s %d -> %d before any syn", msg.Kind, msg.From, msg.To)
+			}
+			if msg.Kind == "rst" {
+				delete(open, p)
+			}
+		}
+	}
+
+	return problems
+}
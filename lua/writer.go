@@ -1,55 +1,205 @@
 package lua
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/samaelod/nabu/types"
 )
 
+// tagName is the struct tag naming the Lua key of a field.
+const tagName = "lua"
+
+// WriteConfig writes cfg as a Lua scenario that ReadLuaConfig reads back
+// unchanged. Every field of types.Config is written except zero values of
+// fields tagged omitempty.
 func WriteConfig(w io.Writer, cfg *types.Config) error {
-	fmt.Fprintln(w, "local config = {}")
-	fmt.Fprintln(w)
-
-	// Globals
-	fmt.Fprintln(w, "-- GLOBALS ----------------------------------------")
-	fmt.Fprintln(w, "config.globals = {")
-	fmt.Fprintf(w, "\tprotocol = %q,\n", cfg.Globals.Protocol)
-	fmt.Fprintf(w, "\tplay_mode = %q,\n", cfg.Globals.PlayMode)
-	fmt.Fprintf(w, "\ttimeout = %d,\n", cfg.Globals.Timeout)
-	fmt.Fprintf(w, "\tdelay = %d,\n", cfg.Globals.Delay)
-	fmt.Fprintln(w, "}")
-	fmt.Fprintln(w)
-
-	// Endpoints
-	fmt.Fprintln(w, "-- ENDPOINTS --------------------------------------")
-	fmt.Fprintln(w, "config.endpoints = {")
-	for _, ep := range cfg.Endpoints {
-		fmt.Fprintln(w, "\t{")
-		fmt.Fprintf(w, "\t\tid = %d,\n", ep.ID)
-		fmt.Fprintf(w, "\t\tkind = %q,\n", ep.Kind)
-		fmt.Fprintf(w, "\t\taddress = %q,\n", ep.Address)
-		fmt.Fprintf(w, "\t\tport = %d,\n", ep.Port)
-		fmt.Fprintln(w, "\t},")
-	}
-	fmt.Fprintln(w, "}")
-	fmt.Fprintln(w)
-
-	// Messages
-	fmt.Fprintln(w, "-- MESSAGES ----------------------------------------")
-	fmt.Fprintln(w, "config.messages = {")
-	for _, m := range cfg.Messages {
-		fmt.Fprintln(w, "\t{")
-		fmt.Fprintf(w, "\t\tfrom = %d,\n", m.From)
-		fmt.Fprintf(w, "\t\tto = %d,\n", m.To)
-		fmt.Fprintf(w, "\t\tkind = %q,\n", m.Kind)
-		fmt.Fprintf(w, "\t\tvalue = %q,\n", m.Value)
-		fmt.Fprintf(w, "\t\tt_delta = %d,\n", m.TDelta)
-		fmt.Fprintln(w, "\t},")
-	}
-	fmt.Fprintln(w, "}")
-	fmt.Fprintln(w)
-	fmt.Fprintln(w, "return config")
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "local config = {}")
+	fmt.Fprintln(bw)
+
+	v := reflect.ValueOf(cfg).Elem()
+	for _, f := range fields(v.Type()) {
+		// Section header, e.g. "-- GLOBALS ----"
+		header := "-- " + strings.ToUpper(f.key) + " "
+		fmt.Fprintln(bw, header+strings.Repeat("-", max(50-len(header), 4)))
+		fmt.Fprintf(bw, "config.%s = ", f.key)
+		if err := writeValue(bw, v.Field(f.index), 0); err != nil {
+			return fmt.Errorf("%s: %w", f.key, err)
+		}
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw)
+	}
+
+	fmt.Fprintln(bw, "return config")
+	return bw.Flush()
+}
+
+// WriteFile writes cfg to path, replacing the file only once it has been
+// written completely. A replaced file keeps its permissions.
+func WriteFile(path string, cfg *types.Config) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".nabu-*.lua")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := WriteConfig(tmp, cfg); err != nil {
+		tmp.Close()
+		return err
+	}
+	// CreateTemp makes files only the owner can read
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+type field struct {
+	index     int
+	key       string
+	omitEmpty bool
+}
+
+// fields lists the exported fields of t with their Lua keys, skipping
+// fields tagged "-". Untagged fields are keyed by their Go name, which is
+// what the reader's mapper matches them by.
+func fields(t reflect.Type) []field {
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(sf.Tag.Get(tagName), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = sf.Name
+		}
+		out = append(out, field{index: i, key: key, omitEmpty: opts == "omitempty"})
+	}
+	return out
+}
+
+func writeValue(w *bufio.Writer, v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		w.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		w.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		w.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.String:
+		w.WriteString(quote(v.String()))
+	case reflect.Slice, reflect.Array:
+		return writeList(w, v, depth)
+	case reflect.Struct:
+		return writeTable(w, v, depth)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// writeList writes scalar lists on one line and lists of tables one
+// element per line.
+func writeList(w *bufio.Writer, v reflect.Value, depth int) error {
+	if v.Len() == 0 {
+		w.WriteString("{}")
+		return nil
+	}
+
+	if v.Type().Elem().Kind() != reflect.Struct {
+		w.WriteString("{ ")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			if err := writeValue(w, v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		w.WriteString(" }")
+		return nil
+	}
 
+	indent := strings.Repeat("\t", depth+1)
+	w.WriteString("{\n")
+	for i := 0; i < v.Len(); i++ {
+		w.WriteString(indent)
+		if err := writeValue(w, v.Index(i), depth+1); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+		w.WriteString(",\n")
+	}
+	w.WriteString(strings.Repeat("\t", depth) + "}")
 	return nil
 }
+
+// writeTable writes the fields of a struct, one per line.
+func writeTable(w *bufio.Writer, v reflect.Value, depth int) error {
+	indent := strings.Repeat("\t", depth+1)
+	w.WriteString("{\n")
+	for _, f := range fields(v.Type()) {
+		fv := v.Field(f.index)
+		if f.omitEmpty && (fv.IsZero() || (fv.Kind() == reflect.Slice && fv.Len() == 0)) {
+			continue
+		}
+		w.WriteString(indent + f.key + " = ")
+		if err := writeValue(w, fv, depth+1); err != nil {
+			return fmt.Errorf("%s: %w", f.key, err)
+		}
+		w.WriteString(",\n")
+	}
+	w.WriteString(strings.Repeat("\t", depth) + "}")
+	return nil
+}
+
+// quote returns s as a Lua string literal. Control bytes use decimal
+// escapes, which every Lua version understands.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\%03d`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
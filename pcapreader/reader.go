@@ -99,6 +99,14 @@ func openPacketSource(path string) (packetSource, error) {
 	return &pcapSource{handle: handle}, nil
 }
 
+func closeSource(source packetSource) {
+	if ps, ok := source.(*pcapngSource); ok {
+		ps.file.Close()
+	} else if ps, ok := source.(*pcapSource); ok {
+		ps.handle.Close()
+	}
+}
+
 type pcapSource struct {
 	handle *pcap.Handle
 }
@@ -130,13 +138,7 @@ func ReadPCAP(path string) (*types.Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if ps, ok := source.(*pcapngSource); ok {
-			ps.file.Close()
-		} else if ps, ok := source.(*pcapSource); ok {
-			ps.handle.Close()
-		}
-	}()
+	defer closeSource(source)
 
 	cfg := &types.Config{
 		Globals: types.Globals{
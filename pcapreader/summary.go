@@ -0,0 +1,169 @@
+package pcapreader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Summary describes a capture without turning it into a scenario.
+type Summary struct {
+	Format   string // "pcap" or "pcapng"
+	LinkType string
+	Packets  int
+	Bytes    int64 // Captured bytes
+	First    time.Time
+	Last     time.Time
+
+	Protocols     []ProtocolCount // Most packets first
+	Conversations []Conversation  // Most packets first, at most the top ones (all with top 0)
+	Endpoints     []string        // TCP endpoints ReadPCAP creates, in order of appearance
+}
+
+// ProtocolCount is the number of packets whose innermost decoded layer is
+// Name, e.g. "TCP", "DNS" or "ARP".
+type ProtocolCount struct {
+	Name    string
+	Packets int
+}
+
+// Conversation is the traffic between two addresses, in both directions.
+type Conversation struct {
+	A, B     string // ip:port, or ip without a transport layer
+	Protocol string // Transport, or network layer without one
+	Packets  int
+	Bytes    int64
+}
+
+// Span returns the time between the first and the last packet.
+func (s *Summary) Span() time.Duration {
+	return s.Last.Sub(s.First)
+}
+
+// Summarize reads the capture at path and counts its packets, protocols,
+// conversations and endpoints, keeping the top conversations, or all of
+// them when top is 0. It stops with ctx's error when ctx is done.
+func Summarize(ctx context.Context, path string, top int) (*Summary, error) {
+	if top < 0 {
+		return nil, fmt.Errorf("summarize %s: negative conversation count %d", path, top)
+	}
+	format, err := detectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	source, err := openPacketSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSource(source)
+
+	s := &Summary{Format: format, LinkType: source.LinkType().String()}
+	protocols := make(map[string]int)
+	conversations := make(map[[2]string]*Conversation)
+	endpoints := make(map[string]bool)
+
+	ds := &packetDataSource{src: source, linkType: source.LinkType()}
+	packetSrc := gopacket.NewPacketSource(ds, ds.LinkType())
+	packetSrc.DecodeOptions = gopacket.DecodeOptions{Lazy: true, NoCopy: true}
+
+	// NextPacket rather than Packets, whose reading goroutine would be left
+	// blocked when ctx is done
+	for {
+		if s.Packets%1000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		packet, err := packetSrc.NextPacket()
+		if err == io.EOF || err == io.ErrUnexpectedEOF { // Truncated captures keep what was read
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		meta := packet.Metadata()
+		if s.Packets == 0 || meta.Timestamp.Before(s.First) {
+			s.First = meta.Timestamp
+		}
+		if meta.Timestamp.After(s.Last) {
+			s.Last = meta.Timestamp
+		}
+		s.Packets++
+		s.Bytes += int64(meta.CaptureLength)
+		protocols[protocolOf(packet)]++
+
+		net := packet.NetworkLayer()
+		if net == nil {
+			continue
+		}
+		a, b := net.NetworkFlow().Src().String(), net.NetworkFlow().Dst().String()
+		proto := net.LayerType().String()
+		if tr := packet.TransportLayer(); tr != nil {
+			a += ":" + tr.TransportFlow().Src().String()
+			b += ":" + tr.TransportFlow().Dst().String()
+			proto = tr.LayerType().String()
+			if tr.LayerType() == layers.LayerTypeTCP {
+				for _, ep := range []string{a, b} {
+					if !endpoints[ep] {
+						endpoints[ep] = true
+						s.Endpoints = append(s.Endpoints, ep)
+					}
+				}
+			}
+		}
+
+		// Both directions count towards one conversation
+		key := [2]string{proto + " " + a, proto + " " + b}
+		if key[1] < key[0] {
+			key[0], key[1] = key[1], key[0]
+			a, b = b, a
+		}
+		c, ok := conversations[key]
+		if !ok {
+			c = &Conversation{A: a, B: b, Protocol: proto}
+			conversations[key] = c
+		}
+		c.Packets++
+		c.Bytes += int64(meta.CaptureLength)
+	}
+
+	for name, n := range protocols {
+		s.Protocols = append(s.Protocols, ProtocolCount{Name: name, Packets: n})
+	}
+	sort.Slice(s.Protocols, func(i, j int) bool {
+		pi, pj := s.Protocols[i], s.Protocols[j]
+		return pi.Packets > pj.Packets || pi.Packets == pj.Packets && pi.Name < pj.Name
+	})
+
+	for _, c := range conversations {
+		s.Conversations = append(s.Conversations, *c)
+	}
+	sort.Slice(s.Conversations, func(i, j int) bool {
+		ci, cj := s.Conversations[i], s.Conversations[j]
+		if ci.Packets != cj.Packets {
+			return ci.Packets > cj.Packets
+		}
+		return ci.A+ci.B < cj.A+cj.B
+	})
+	if top > 0 && len(s.Conversations) > top {
+		s.Conversations = s.Conversations[:top]
+	}
+	return s, nil
+}
+
+// protocolOf names the innermost layer of packet that was decoded.
+func protocolOf(packet gopacket.Packet) string {
+	name := "Unknown"
+	for _, l := range packet.Layers() {
+		switch l.LayerType() {
+		case gopacket.LayerTypePayload, gopacket.LayerTypeDecodeFailure, gopacket.LayerTypeFragment:
+		default:
+			name = l.LayerType().String()
+		}
+	}
+	return name
+}
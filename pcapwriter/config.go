@@ -0,0 +1,90 @@
+package pcapwriter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// configEpoch is the timestamp of the first message in captures generated
+// from a scenario, fixed so the same scenario always yields the same file.
+var configEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteConfig writes the messages of cfg as a classic pcap capture. Each
+// message becomes one TCP segment between its endpoints' addresses, with
+// timestamps from the cumulative TDelta.
+func WriteConfig(w io.Writer, cfg *types.Config) error {
+	pw, err := NewWriter(w, false)
+	if err != nil {
+		return err
+	}
+	if err := writeMessages(pw, cfg); err != nil {
+		pw.Close()
+		return err
+	}
+	return pw.Close()
+}
+
+// SaveConfig writes cfg to path, as pcapng when path ends in .pcapng.
+func SaveConfig(path string, cfg *types.Config) error {
+	pw, err := Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeMessages(pw, cfg); err != nil {
+		pw.Close()
+		return err
+	}
+	return pw.Close()
+}
+
+func writeMessages(pw *Writer, cfg *types.Config) error {
+	addrs := make(map[int]Addr, len(cfg.Endpoints))
+	for _, ep := range cfg.Endpoints {
+		addrs[ep.ID] = ParseAddr(ep.Address, ep.Port)
+	}
+
+	ts := configEpoch
+	for i, msg := range cfg.Messages {
+		ts = ts.Add(time.Duration(msg.TDelta) * time.Millisecond)
+
+		from, ok := addrs[msg.From]
+		if !ok {
+			return fmt.Errorf("message %d: endpoint %d not found", i, msg.From)
+		}
+		to, ok := addrs[msg.To]
+		if !ok {
+			return fmt.Errorf("message %d: endpoint %d not found", i, msg.To)
+		}
+
+		payload, err := hex.DecodeString(msg.Value)
+		if err != nil {
+			return fmt.Errorf("message %d: invalid hex payload: %v", i, err)
+		}
+
+		switch msg.Kind {
+		case "syn":
+			err = pw.Packet(ts, from, to, SYN, payload)
+		case "syn-ack":
+			err = pw.Packet(ts, from, to, SYN|ACK, payload)
+		case "ack":
+			err = pw.Packet(ts, from, to, ACK, payload)
+		case "data", "psh", "push":
+			err = pw.Data(ts, from, to, payload)
+		case "fin":
+			err = pw.Packet(ts, from, to, FIN|ACK, payload)
+		case "rst":
+			err = pw.Packet(ts, from, to, RST|ACK, payload)
+		default:
+			// The engine skips unknown kinds as well
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	return nil
+}
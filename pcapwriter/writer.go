@@ -0,0 +1,319 @@
+// Package pcapwriter writes synthetic Ethernet/IP/TCP captures, so traffic
+// nabu produced or describes can be opened in Wireshark.
+package pcapwriter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+const (
+	snapLen = 65535
+	// mss splits payloads the way a 1500-byte Ethernet MTU would.
+	mss    = 1460
+	window = 65535
+)
+
+var errClosed = errors.New("pcapwriter: writer closed")
+
+// Flags are the TCP control bits of a packet.
+type Flags uint8
+
+const (
+	SYN Flags = 1 << iota
+	ACK
+	PSH
+	FIN
+	RST
+)
+
+// Addr is one end of a TCP connection.
+type Addr struct {
+	IP   net.IP
+	Port int
+}
+
+func (a Addr) String() string {
+	return net.JoinHostPort(a.IP.String(), fmt.Sprint(a.Port))
+}
+
+// AddrOf converts a *net.TCPAddr; other address types yield the zero Addr.
+func AddrOf(a net.Addr) Addr {
+	if tcp, ok := a.(*net.TCPAddr); ok {
+		return Addr{IP: tcp.IP, Port: tcp.Port}
+	}
+	return Addr{}
+}
+
+// ParseAddr builds an Addr from a host string and port. Hostnames that are
+// not IP literals map to 0.0.0.0.
+func ParseAddr(host string, port int) Addr {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ip = net.IPv4zero
+	}
+	return Addr{IP: ip, Port: port}
+}
+
+type packetWriter interface {
+	WritePacket(ci gopacket.CaptureInfo, data []byte) error
+}
+
+// half is the sending state of one direction of a connection.
+type half struct {
+	isn  uint32
+	next uint32
+}
+
+// Writer serializes TCP packets into a pcap or pcapng stream, keeping
+// sequence and acknowledgement numbers consistent per connection. It is safe
+// for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	pw     packetWriter
+	ng     *pcapgo.NgWriter
+	buf    *bufio.Writer
+	file   io.Closer
+	halves map[string]*half
+	ipID   uint16
+	closed bool
+}
+
+// Create writes a capture to path; a .pcapng extension selects pcapng,
+// anything else classic pcap.
+func Create(path string) (*Writer, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f, strings.EqualFold(filepath.Ext(path), ".pcapng"))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.file = f
+	return w, nil
+}
+
+// NewWriter writes a capture to out. Close flushes it but does not close out.
+func NewWriter(out io.Writer, ng bool) (*Writer, error) {
+	w := &Writer{buf: bufio.NewWriter(out), halves: make(map[string]*half)}
+
+	if ng {
+		nw, err := pcapgo.NewNgWriter(w.buf, layers.LinkTypeEthernet)
+		if err != nil {
+			return nil, err
+		}
+		w.ng = nw
+		w.pw = nw
+		return w, nil
+	}
+
+	pw := pcapgo.NewWriterNanos(w.buf)
+	if err := pw.WriteFileHeader(snapLen, layers.LinkTypeEthernet); err != nil {
+		return nil, err
+	}
+	w.pw = pw
+	return w, nil
+}
+
+// Handshake writes SYN, SYN-ACK and ACK between client and server.
+func (w *Writer) Handshake(ts time.Time, client, server Addr) error {
+	if err := w.Packet(ts, client, server, SYN, nil); err != nil {
+		return err
+	}
+	if err := w.Packet(ts, server, client, SYN|ACK, nil); err != nil {
+		return err
+	}
+	return w.Packet(ts, client, server, ACK, nil)
+}
+
+// Data writes payload from one end to the other, split into MSS-sized
+// segments.
+func (w *Writer) Data(ts time.Time, from, to Addr, payload []byte) error {
+	for len(payload) > 0 {
+		n := min(len(payload), mss)
+		if err := w.Packet(ts, from, to, PSH|ACK, payload[:n]); err != nil {
+			return err
+		}
+		payload = payload[n:]
+	}
+	return nil
+}
+
+// Fin writes a FIN from one end.
+func (w *Writer) Fin(ts time.Time, from, to Addr) error {
+	return w.Packet(ts, from, to, FIN|ACK, nil)
+}
+
+// Reset writes a RST from one end.
+func (w *Writer) Reset(ts time.Time, from, to Addr) error {
+	return w.Packet(ts, from, to, RST|ACK, nil)
+}
+
+// Packet writes a single TCP segment. Sequence numbers start from a value
+// derived from the address pair, so the same input always produces the same
+// capture; a SYN restarts the sending direction.
+func (w *Writer) Packet(ts time.Time, from, to Addr, flags Flags, payload []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errClosed
+	}
+
+	out := w.half(from, to)
+	in := w.half(to, from)
+
+	seq := out.next
+	if flags&SYN != 0 {
+		seq = out.isn
+		out.next = out.isn + 1 + uint32(len(payload))
+	} else {
+		out.next += uint32(len(payload))
+		if flags&FIN != 0 {
+			out.next++
+		}
+	}
+
+	tcp := &layers.TCP{
+		SrcPort: layers.TCPPort(from.Port),
+		DstPort: layers.TCPPort(to.Port),
+		Seq:     seq,
+		SYN:     flags&SYN != 0,
+		ACK:     flags&ACK != 0,
+		PSH:     flags&PSH != 0,
+		FIN:     flags&FIN != 0,
+		RST:     flags&RST != 0,
+		Window:  window,
+	}
+	if tcp.ACK {
+		tcp.Ack = in.next
+	}
+
+	data, err := w.serialize(from, to, tcp, payload)
+	if err != nil {
+		return err
+	}
+
+	ci := gopacket.CaptureInfo{Timestamp: ts, CaptureLength: len(data), Length: len(data)}
+	return w.pw.WritePacket(ci, data)
+}
+
+// Forget drops the sequence numbers of the connection between a and b, so
+// a later connection between the same addresses starts afresh.
+func (w *Writer) Forget(a, b Addr) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.halves, a.String()+">"+b.String())
+	delete(w.halves, b.String()+">"+a.String())
+}
+
+func (w *Writer) half(from, to Addr) *half {
+	key := from.String() + ">" + to.String()
+	h, ok := w.halves[key]
+	if !ok {
+		isn := crc32.ChecksumIEEE([]byte(key))
+		// Directions first seen without a SYN behave as if the handshake
+		// happened before the capture started.
+		h = &half{isn: isn, next: isn + 1}
+		w.halves[key] = h
+	}
+	return h
+}
+
+func (w *Writer) serialize(from, to Addr, tcp *layers.TCP, payload []byte) ([]byte, error) {
+	eth := &layers.Ethernet{SrcMAC: mac(from.IP), DstMAC: mac(to.IP)}
+
+	var network gopacket.SerializableLayer
+	src4, dst4 := from.IP.To4(), to.IP.To4()
+	if src4 != nil && dst4 != nil {
+		w.ipID++
+		ip := &layers.IPv4{
+			Version:  4,
+			IHL:      5,
+			TTL:      64,
+			Id:       w.ipID,
+			Flags:    layers.IPv4DontFragment,
+			Protocol: layers.IPProtocolTCP,
+			SrcIP:    src4,
+			DstIP:    dst4,
+		}
+		eth.EthernetType = layers.EthernetTypeIPv4
+		tcp.SetNetworkLayerForChecksum(ip)
+		network = ip
+	} else {
+		ip := &layers.IPv6{
+			Version:    6,
+			HopLimit:   64,
+			NextHeader: layers.IPProtocolTCP,
+			SrcIP:      from.IP.To16(),
+			DstIP:      to.IP.To16(),
+		}
+		if ip.SrcIP == nil || ip.DstIP == nil {
+			return nil, fmt.Errorf("pcapwriter: invalid address %s -> %s", from, to)
+		}
+		eth.EthernetType = layers.EthernetTypeIPv6
+		tcp.SetNetworkLayerForChecksum(ip)
+		network = ip
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, network, tcp, gopacket.Payload(payload)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mac derives a stable locally administered MAC address from ip.
+func mac(ip net.IP) net.HardwareAddr {
+	ip = ip.To16()
+	if ip == nil {
+		ip = net.IPv6zero
+	}
+	return net.HardwareAddr{0x02, 0x00, ip[12], ip[13], ip[14], ip[15]}
+}
+
+// Close flushes buffered packets and closes the file opened by Create.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var err error
+	if w.ng != nil {
+		err = w.ng.Flush()
+	}
+	if ferr := w.buf.Flush(); err == nil {
+		err = ferr
+	}
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
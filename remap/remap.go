@@ -0,0 +1,232 @@
+package remap
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/samaelod/nabu/types"
+)
+
+// Rule rewrites endpoint addresses and ports. Rules are written as
+// "<match> -> <target>", for example:
+//
+//	10.1.0.0/16 -> 127.0.0.1
+//	10.1.0.5:443 -> 127.0.0.1:8443
+//	port 443 -> 8443
+//	endpoint 3 -> 127.0.0.1:9000
+type Rule struct {
+	Raw string
+
+	Endpoint  int        // Endpoint ID to override, -1 when not an endpoint rule
+	Network   *net.IPNet // Address match, nil for port and endpoint rules
+	Port      int        // Port match, 0 matches any port
+	ToAddress string     // Replacement address, empty keeps the original
+	ToPort    int        // Replacement port, 0 keeps the original
+}
+
+// Parse parses a single remap rule.
+func Parse(s string) (Rule, error) {
+	r := Rule{Raw: s, Endpoint: -1}
+
+	lhs, rhs, ok := strings.Cut(s, "->")
+	if !ok {
+		return r, fmt.Errorf("remap %q: missing \"->\"", s)
+	}
+	lhs = strings.TrimSpace(lhs)
+	rhs = strings.TrimSpace(rhs)
+	if lhs == "" || rhs == "" {
+		return r, fmt.Errorf("remap %q: empty match or target", s)
+	}
+
+	fields := strings.Fields(lhs)
+	switch {
+	case len(fields) == 2 && (fields[0] == "endpoint" || fields[0] == "ep"):
+		id, err := strconv.Atoi(fields[1])
+		if err != nil || id < 0 {
+			return r, fmt.Errorf("remap %q: invalid endpoint id %q", s, fields[1])
+		}
+		r.Endpoint = id
+
+	case len(fields) == 2 && fields[0] == "port":
+		port, err := parsePort(fields[1])
+		if err != nil {
+			return r, fmt.Errorf("remap %q: %w", s, err)
+		}
+		r.Port = port
+
+		toPort, err := parsePort(rhs)
+		if err != nil {
+			return r, fmt.Errorf("remap %q: %w", s, err)
+		}
+		r.ToPort = toPort
+		return r, nil
+
+	case len(fields) == 1:
+		network, port, err := parseMatch(lhs)
+		if err != nil {
+			return r, fmt.Errorf("remap %q: %w", s, err)
+		}
+		r.Network = network
+		r.Port = port
+
+	default:
+		return r, fmt.Errorf("remap %q: unrecognised match %q", s, lhs)
+	}
+
+	addr, port, err := parseTarget(rhs)
+	if err != nil {
+		return r, fmt.Errorf("remap %q: %w", s, err)
+	}
+	r.ToAddress = addr
+	r.ToPort = port
+
+	return r, nil
+}
+
+// ParseAll parses a list of rules, stopping at the first invalid one.
+func ParseAll(specs []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(specs))
+	for _, s := range specs {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		r, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Apply rewrites the endpoints of cfg in place and returns how many
+// endpoints changed. Every endpoint is matched against its original address
+// and port. An endpoint rule replaces all other rules for that endpoint;
+// otherwise the first matching address rule and the first matching port rule
+// are applied, with an address rule's port taking precedence.
+func Apply(cfg *types.Config, rules []Rule) int {
+	if cfg == nil || len(rules) == 0 {
+		return 0
+	}
+
+	changed := 0
+	for i := range cfg.Endpoints {
+		ep := &cfg.Endpoints[i]
+		addr, port := rewrite(ep.ID, ep.Address, ep.Port, rules)
+		if addr != ep.Address || port != ep.Port {
+			ep.Address = addr
+			ep.Port = port
+			changed++
+		}
+	}
+	return changed
+}
+
+func rewrite(id int, addr string, port int, rules []Rule) (string, int) {
+	for _, r := range rules {
+		if r.Endpoint >= 0 && r.Endpoint == id {
+			return pick(r.ToAddress, addr), pickPort(r.ToPort, port)
+		}
+	}
+
+	newAddr, newPort := addr, port
+	addrDone, portDone := false, false
+	ip := net.ParseIP(addr)
+
+	for _, r := range rules {
+		switch {
+		case r.Endpoint >= 0:
+			continue
+		case r.Network != nil:
+			if addrDone || ip == nil || !r.Network.Contains(ip) {
+				continue
+			}
+			if r.Port != 0 && r.Port != port {
+				continue
+			}
+			newAddr = pick(r.ToAddress, addr)
+			if r.ToPort != 0 {
+				newPort = r.ToPort
+				portDone = true
+			}
+			addrDone = true
+		case r.Port != 0:
+			if portDone || r.Port != port {
+				continue
+			}
+			newPort = r.ToPort
+			portDone = true
+		}
+	}
+
+	return newAddr, newPort
+}
+
+func pick(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
+
+func pickPort(v, fallback int) int {
+	if v == 0 {
+		return fallback
+	}
+	return v
+}
+
+// parseMatch accepts "ip", "ip:port", "[ipv6]:port" or "cidr".
+func parseMatch(s string) (*net.IPNet, int, error) {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network, 0, nil
+	}
+
+	host, port := s, 0
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		host = h
+		port, err = parsePort(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, network, err := net.ParseCIDR(host); err == nil {
+			return network, port, nil
+		}
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid address %q", host)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, port, nil
+}
+
+// parseTarget accepts "host", "host:port" or ":port".
+func parseTarget(s string) (string, int, error) {
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		port, err := parsePort(p)
+		if err != nil {
+			return "", 0, err
+		}
+		return h, port, nil
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	return s, 0, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
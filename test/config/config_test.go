@@ -0,0 +1,51 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samaelod/nabu/config"
+)
+
+func TestLoadDefaultInvalid(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("nabu.json", []byte(`{"log_level": "loud"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failure is cached, and so must be reported on every call
+	for i := range 2 {
+		cfg, err := config.LoadDefault()
+		if err == nil {
+			t.Fatalf("call %d: no error for an invalid log_level", i+1)
+		}
+		if cfg == nil || cfg.LogLevel != "info" {
+			t.Fatalf("call %d: got config %+v, want the defaults", i+1, cfg)
+		}
+	}
+}
+
+func TestLuaSandboxOptIn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nabu.json")
+	if err := os.WriteFile(path, []byte(`{"log_level": "debug"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LuaSandbox || cfg.LuaTimeoutMs != 0 || cfg.LuaMemoryMB != 0 {
+		t.Errorf("scripts sandboxed or limited without opting in: %+v", cfg)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"lua_sandbox": true, "lua_timeout_ms": 5000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = config.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.LuaSandbox || cfg.LuaTimeoutMs != 5000 {
+		t.Errorf("opt-in not applied: %+v", cfg)
+	}
+}
@@ -0,0 +1,138 @@
+package engine_test
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+// sourceRecorder accepts connections and records the address each came
+// from, in order.
+type sourceRecorder struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	addrs []*net.TCPAddr
+}
+
+func recordSources(t *testing.T) *sourceRecorder {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	r := &sourceRecorder{ln: ln}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r.mu.Lock()
+			r.addrs = append(r.addrs, c.RemoteAddr().(*net.TCPAddr))
+			r.mu.Unlock()
+			go func() {
+				defer c.Close()
+				io.Copy(io.Discard, c)
+			}()
+		}
+	}()
+	return r
+}
+
+func (r *sourceRecorder) port() int {
+	return r.ln.Addr().(*net.TCPAddr).Port
+}
+
+// sources returns the source IPs seen so far.
+func (r *sourceRecorder) sources() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, a := range r.addrs {
+		out = append(out, a.IP.String())
+	}
+	return out
+}
+
+func TestBindSourceAddress(t *testing.T) {
+	srv := recordSources(t)
+	port := freePort(t, "127.0.0.2")
+	cfg := &types.Config{
+		Globals: types.Globals{Iterations: 2},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: srv.port()},
+			// A bound port is in TIME_WAIT after its connection, so only the
+			// address of the repeating client is bound
+			{ID: 2, Kind: "client", Address: "127.0.0.2", Port: port, Bind: true, Iterations: 1},
+			{ID: 3, Kind: "client", Address: "127.0.0.3", Bind: true},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "syn"},
+			{From: 3, To: 1, Kind: "syn"},
+		},
+	}
+	e := engine.NewEngine(cfg, "", 1000, 1000, 0)
+	defer e.Close()
+
+	e.StartEndpoint(2)
+	waitStatus(t, e, types.StatusCompleted, 2)
+	e.StartEndpoint(3)
+	waitStatus(t, e, types.StatusCompleted, 3)
+	time.Sleep(100 * time.Millisecond)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.addrs) != 3 {
+		t.Fatalf("server saw %d connections, want 3:\n%s", len(srv.addrs), e.Log.ReadAll())
+	}
+	if a := srv.addrs[0]; a.IP.String() != "127.0.0.2" || a.Port != port {
+		t.Errorf("endpoint 2 connected from %v, want 127.0.0.2:%d", a, port)
+	}
+	for _, a := range srv.addrs[1:] {
+		if a.IP.String() != "127.0.0.3" {
+			t.Errorf("endpoint 3 connected from %v, want 127.0.0.3", a)
+		}
+	}
+}
+
+func TestSourcePoolPerEndpoint(t *testing.T) {
+	srv := recordSources(t)
+	cfg := &types.Config{
+		Globals: types.Globals{Iterations: 3},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: srv.port()},
+			{ID: 2, Kind: "client", Address: "127.0.0.1", SourcePool: []string{"127.0.0.4"}},
+			{ID: 3, Kind: "client", Address: "127.0.0.1", SourcePool: []string{"127.0.0.2", "127.0.0.3"}},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "syn"},
+			{From: 3, To: 1, Kind: "syn"},
+		},
+	}
+	e := engine.NewEngine(cfg, "", 1000, 1000, 0)
+	defer e.Close()
+
+	// The connections of 2 must not move 3 along its pool
+	e.StartEndpoint(2)
+	waitStatus(t, e, types.StatusCompleted, 2)
+	e.StartEndpoint(3)
+	waitStatus(t, e, types.StatusCompleted, 3)
+	time.Sleep(100 * time.Millisecond)
+
+	got := srv.sources()
+	want := []string{"127.0.0.4", "127.0.0.4", "127.0.0.4", "127.0.0.2", "127.0.0.3", "127.0.0.2"}
+	if len(got) != len(want) {
+		t.Fatalf("server saw sources %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("server saw sources %v, want %v", got, want)
+		}
+	}
+}
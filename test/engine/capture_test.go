@@ -0,0 +1,86 @@
+package engine_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+// A client bound to its address dials the same tuple on every iteration;
+// each connection must be captured with its own handshake and its data once.
+func TestCaptureRedialedTuple(t *testing.T) {
+	cfg := &types.Config{
+		Globals: types.Globals{Timeout: 1000, Iterations: 3, ThinkTime: 100},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: freePort(t, "127.0.0.1")},
+			{ID: 2, Kind: "client", Address: "127.0.0.1", Port: freePort(t, "127.0.0.1"), Bind: true},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "syn"},
+			{From: 2, To: 1, Kind: "data", Value: "01"},
+			// A reset leaves no TIME_WAIT in the way of the next dial
+			{From: 2, To: 1, Kind: "rst", TDelta: 50},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "run.pcapng")
+	e := engine.NewEngine(cfg, "", 100, 1000, 0)
+	if err := e.EnableCapture(path); err != nil {
+		t.Fatal(err)
+	}
+
+	e.StartEndpoint(1)
+	time.Sleep(50 * time.Millisecond)
+	e.StartEndpoint(2)
+	deadline := time.Now().Add(5 * time.Second)
+	for e.IsRunning(2) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := e.GetStatus(2); st != types.StatusCompleted {
+		t.Fatalf("client status %v, want completed", st)
+	}
+	e.StopAll()
+	e.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var syns, data int
+	for {
+		raw, _, err := r.ReadPacketData()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+		tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+		if !ok {
+			continue
+		}
+		if tcp.SYN && !tcp.ACK {
+			syns++
+		}
+		if len(tcp.Payload) > 0 {
+			data++
+		}
+	}
+	if syns != 3 || data != 3 {
+		t.Errorf("captured %d handshakes and %d data segments, want 3 of each", syns, data)
+	}
+}
@@ -0,0 +1,151 @@
+package engine_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+// peerResult is what the test's end of the connection saw.
+type peerResult struct {
+	data []byte
+	err  error // Error that ended reading, nil for EOF
+}
+
+// acceptOne accepts a single connection on a new listener and hands it to
+// serve. It returns the port and the result of serve.
+func acceptOne(t *testing.T, serve func(net.Conn) peerResult) (int, <-chan peerResult) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	result := make(chan peerResult, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			result <- peerResult{err: err}
+			return
+		}
+		defer c.Close()
+		result <- serve(c)
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, result
+}
+
+// readAll reads c until EOF or an error.
+func readAll(c net.Conn) peerResult {
+	c.SetReadDeadline(time.Now().Add(3 * time.Second))
+	data, err := io.ReadAll(c)
+	return peerResult{data: data, err: err}
+}
+
+func closeScenario(port int, globals types.Globals, kinds ...string) *types.Config {
+	cfg := &types.Config{
+		Globals: globals,
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: port},
+			{ID: 2, Kind: "client", Address: "127.0.0.1"},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "syn"},
+			{From: 2, To: 1, Kind: "data", Value: "6869"},
+		},
+	}
+	for _, kind := range kinds {
+		cfg.Messages = append(cfg.Messages, types.Message{From: 2, To: 1, Kind: kind})
+	}
+	return cfg
+}
+
+// runClient runs endpoint 2 of cfg to completion and returns its log.
+func runClient(t *testing.T, cfg *types.Config) string {
+	t.Helper()
+	e := engine.NewEngine(cfg, "", 1000, 1000, 0)
+	defer e.Close()
+	e.StartEndpoint(2)
+	waitStatus(t, e, types.StatusCompleted, 2)
+	// Let the receive loop report what the peer did last
+	time.Sleep(200 * time.Millisecond)
+	e.StopAll()
+	return e.Log.ReadAll()
+}
+
+func TestFinHalfClose(t *testing.T) {
+	// The peer sees the data then EOF, and can still answer before closing
+	port, result := acceptOne(t, func(c net.Conn) peerResult {
+		r := readAll(c)
+		if r.err == nil {
+			_, r.err = c.Write([]byte("ok"))
+		}
+		return r
+	})
+	log := runClient(t, closeScenario(port, types.Globals{FinDrain: 2000}, "fin"))
+
+	r := <-result
+	if r.err != nil || string(r.data) != "hi" {
+		t.Fatalf("peer read %q with %v, want hi then EOF", r.data, r.err)
+	}
+	if !strings.Contains(log, "Peer 1 closed connection from 2") {
+		t.Errorf("fin did not wait for the peer to close:\n%s", log)
+	}
+}
+
+func TestFinDrainExpires(t *testing.T) {
+	// The peer sees EOF but keeps its side open
+	port, result := acceptOne(t, func(c net.Conn) peerResult {
+		r := readAll(c)
+		time.Sleep(500 * time.Millisecond)
+		return r
+	})
+	log := runClient(t, closeScenario(port, types.Globals{FinDrain: 100}, "fin"))
+
+	if r := <-result; r.err != nil || string(r.data) != "hi" {
+		t.Fatalf("peer read %q with %v, want hi then EOF", r.data, r.err)
+	}
+	if !strings.Contains(log, "did not close within 100ms") {
+		t.Errorf("no warning once fin_drain expired:\n%s", log)
+	}
+}
+
+func TestRstResetsPeer(t *testing.T) {
+	port, result := acceptOne(t, readAll)
+	runClient(t, closeScenario(port, types.Globals{}, "rst"))
+
+	r := <-result
+	if !errors.Is(r.err, syscall.ECONNRESET) {
+		t.Errorf("peer read ended with %v, want a connection reset", r.err)
+	}
+}
+
+func TestUnknownKindWarns(t *testing.T) {
+	port, _ := acceptOne(t, readAll)
+	cfg := closeScenario(port, types.Globals{}, "bogus")
+	e := engine.NewEngine(cfg, "", 1000, 1000, 0)
+	defer e.Close()
+	sub := e.Events.Subscribe(256, engine.DropOldest)
+
+	e.StartEndpoint(2)
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case ev := <-sub.C:
+			if strings.Contains(ev.Text, `Unknown message kind "bogus"`) {
+				if ev.Level != engine.LevelWarn {
+					t.Errorf("unknown kind logged at %v, want warn", ev.Level)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("no warning for an unknown message kind")
+		}
+	}
+}
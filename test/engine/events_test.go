@@ -0,0 +1,78 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+)
+
+func TestBusPolicies(t *testing.T) {
+	bus := engine.NewBus()
+	newest := bus.Subscribe(2, engine.DropNewest)
+	oldest := bus.Subscribe(2, engine.DropOldest)
+	block := bus.Subscribe(1, engine.Block)
+
+	received := make(chan []int, 1)
+	go func() {
+		var got []int
+		for ev := range block.C {
+			got = append(got, ev.Bytes)
+		}
+		received <- got
+	}()
+
+	for i := 1; i <= 4; i++ {
+		bus.Publish(engine.Event{Type: engine.EventSent, Bytes: i})
+	}
+	bus.Close()
+
+	drain := func(s *engine.Subscription) []int {
+		var got []int
+		for ev := range s.C {
+			got = append(got, ev.Bytes)
+		}
+		return got
+	}
+
+	if got := drain(newest); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("DropNewest got %v, want [1 2]", got)
+	}
+	if newest.Dropped() != 2 {
+		t.Errorf("DropNewest dropped %d, want 2", newest.Dropped())
+	}
+	if got := drain(oldest); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("DropOldest got %v, want [3 4]", got)
+	}
+
+	select {
+	case got := <-received:
+		if len(got) != 4 {
+			t.Errorf("Block got %v, want all 4 events", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Block subscriber did not finish")
+	}
+}
+
+func TestBusUnsubscribeReleasesPublisher(t *testing.T) {
+	bus := engine.NewBus()
+	sub := bus.Subscribe(1, engine.Block)
+
+	bus.Publish(engine.Event{Type: engine.EventLog})
+
+	done := make(chan struct{})
+	go func() {
+		bus.Publish(engine.Event{Type: engine.EventLog}) // blocks: buffer full
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	sub.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish still blocked after unsubscribe")
+	}
+}
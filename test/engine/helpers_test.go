@@ -0,0 +1,37 @@
+package engine_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+// freePort returns a port on host that nothing listens on. The test is
+// skipped when host cannot be listened on, as 127.0.0.2 on some systems.
+func freePort(t *testing.T, host string) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Skipf("cannot use %s: %v", host, err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// waitStatus polls each of ids until its status is want, failing the test
+// with the engine log if one is not within a few seconds.
+func waitStatus(t *testing.T, e *engine.Engine, want types.EndpointStatus, ids ...int) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for _, id := range ids {
+		for e.GetStatus(id) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("endpoint %d is %v, not %v:\n%s", id, e.GetStatus(id), want, e.Log.ReadAll())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package engine_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestLoadStages(t *testing.T) {
+	cfg := &types.Config{
+		Globals: types.Globals{Load: types.LoadProfile{
+			StartRate: 0,
+			Stages: []types.LoadStage{
+				{Duration: 300, Rate: 10},                // Ramp from the start rate
+				{Duration: 200, Rate: 20, Shape: "step"}, // Jump
+				{Duration: 300, Rate: 0},                 // Ramp down from the step
+				{Duration: 0, Rate: 50},                  // Empty stages are skipped
+				{Duration: 200, Rate: 5, Shape: "step"},
+			},
+		}},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: 9},
+			{ID: 2, Kind: "client", Address: "127.0.0.1"},
+		},
+		Messages: []types.Message{{From: 2, To: 1, Kind: "data", Value: "01"}},
+	}
+	e := engine.NewEngine(cfg, "", 100, 1000, 0)
+	defer e.Close()
+
+	if err := e.StartLoad(); err != nil {
+		t.Fatal(err)
+	}
+	rates := make(map[int][]float64)
+	var stages []int
+	deadline := time.Now().Add(3 * time.Second)
+	for e.LoadRunning() && time.Now().Before(deadline) {
+		st := e.LoadStats()
+		if st.Stage >= 0 {
+			if len(stages) == 0 || stages[len(stages)-1] != st.Stage {
+				stages = append(stages, st.Stage)
+			}
+			rates[st.Stage] = append(rates[st.Stage], st.Rate)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if e.LoadRunning() {
+		t.Fatal("load profile still running")
+	}
+
+	if !slices.Equal(stages, []int{0, 1, 2, 4}) {
+		t.Fatalf("went through stages %v, want 0, 1, 2 and 4", stages)
+	}
+	// Ramps move monotonically between the rates around them and pass
+	// through values in between; steps hold their rate
+	ramp := func(stage int, from, to float64) {
+		t.Helper()
+		between := false
+		for i, r := range rates[stage] {
+			if r < math.Min(from, to)-1e-9 || r > math.Max(from, to)+1e-9 {
+				t.Errorf("stage %d at rate %v, outside %v to %v", stage, r, from, to)
+			}
+			if i > 0 && (to-from)*(r-rates[stage][i-1]) < -1e-9 {
+				t.Errorf("stage %d rates %v do not go from %v to %v", stage, rates[stage], from, to)
+				return
+			}
+			if math.Abs(r-from) > 1 && math.Abs(r-to) > 1 {
+				between = true
+			}
+		}
+		if !between {
+			t.Errorf("stage %d rates %v jump from %v to %v", stage, rates[stage], from, to)
+		}
+	}
+	ramp(0, 0, 10)
+	ramp(2, 20, 0)
+	step := func(stage int, rate float64) {
+		t.Helper()
+		for _, r := range rates[stage] {
+			if r != rate {
+				t.Errorf("stage %d at rate %v, want %v", stage, r, rate)
+				return
+			}
+		}
+	}
+	step(1, 20)
+	step(4, 5)
+	if st := e.LoadStats(); st.Running || st.Started == 0 || st.Completed != st.Started {
+		t.Errorf("unexpected stats once finished: %+v", st)
+	}
+}
+
+func TestLoadMaxSessions(t *testing.T) {
+	cfg := &types.Config{
+		Globals: types.Globals{Load: types.LoadProfile{
+			MaxSessions: 2,
+			// One arrival per scheduler tick, 30 in all
+			Stages: []types.LoadStage{{Duration: 300, Rate: 100, Shape: "step"}},
+		}},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: 9},
+			{ID: 2, Kind: "client", Address: "127.0.0.1"},
+		},
+		// Sessions last about 200ms without opening a connection
+		Messages: []types.Message{{From: 2, To: 1, Kind: "data", Value: "01", TDelta: 200}},
+	}
+	e := engine.NewEngine(cfg, "", 100, 1000, 0)
+	defer e.Close()
+
+	if err := e.StartLoad(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for e.LoadRunning() && time.Now().Before(deadline) {
+		if st := e.LoadStats(); st.Active > 2 {
+			t.Fatalf("%d sessions active, more than max_sessions", st.Active)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if e.LoadRunning() {
+		t.Fatal("load profile still running")
+	}
+
+	st := e.LoadStats()
+	if st.Started+st.Dropped != 30 {
+		t.Errorf("%d started and %d dropped, want 30 arrivals", st.Started, st.Dropped)
+	}
+	if st.Started < 2 || st.Started > 4 || st.Dropped == 0 {
+		t.Errorf("%d sessions started, %d dropped; want 2 to 4 started with 2 at a time", st.Started, st.Dropped)
+	}
+	if st.Completed != st.Started || st.Failed != 0 || st.Active != 0 || len(st.Latencies) != st.Completed {
+		t.Errorf("unexpected stats once finished: %+v", st)
+	}
+	if st.Running || st.Stage != 0 || st.Rate != 100 {
+		t.Errorf("running %v at stage %d, rate %v; want stopped at stage 0, rate 100", st.Running, st.Stage, st.Rate)
+	}
+	for _, l := range st.Latencies {
+		if l < 200*time.Millisecond {
+			t.Errorf("latency %v shorter than the session", l)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestLoggerLevelAndJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	log := engine.NewLoggerWithOptions(engine.LoggerOptions{Path: path, Level: engine.LevelWarn, JSON: true})
+	log.Write("dropped below threshold")
+	log.Close()
+
+	if got := log.ReadAll(); got != "" {
+		t.Errorf("info line kept at warn threshold: %q", got)
+	}
+
+	log = engine.NewLoggerWithOptions(engine.LoggerOptions{Path: path, Level: engine.LevelInfo, JSON: true})
+	log.Write("hello")
+	log.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &line); err != nil {
+		t.Fatalf("not a JSON line: %q: %v", data, err)
+	}
+	if line["level"] != "info" || line["msg"] != "hello" {
+		t.Errorf("unexpected entry %v", line)
+	}
+	if ts, _ := line["ts"].(string); !strings.Contains(ts, ".") {
+		t.Errorf("timestamp %q has no milliseconds", ts)
+	}
+}
+
+func TestLoggerRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	opts := engine.LoggerOptions{Path: path, MaxSize: 100, MaxBackups: 2}
+
+	// Each logger flushes on Close, so every run ends up as one write
+	for i := 0; i < 5; i++ {
+		log := engine.NewLoggerWithOptions(opts)
+		log.Write(strings.Repeat("x", 80))
+		log.Close()
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("missing %s: %v", filepath.Base(name), err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond log_max_backups kept")
+	}
+
+	// Lines written by one logger are buffered together, and still rotate
+	// as whole lines
+	path = filepath.Join(t.TempDir(), "run.log")
+	log := engine.NewLoggerWithOptions(engine.LoggerOptions{Path: path, JSON: true, MaxSize: 400})
+	for i := 0; i < 40; i++ {
+		log.Write(strings.Repeat("y", 10+i*7%60))
+	}
+	log.Close()
+
+	files, _ := filepath.Glob(path + "*")
+	if len(files) < 3 {
+		t.Fatalf("got %d files, want the log rotated at least twice", len(files))
+	}
+	lines := 0
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 400 {
+			t.Errorf("%s has %d bytes, more than the limit", filepath.Base(name), len(data))
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if !json.Valid([]byte(line)) {
+				t.Errorf("%s: line split by rotation: %q", filepath.Base(name), line)
+			}
+			lines++
+		}
+	}
+	if lines != 40 {
+		t.Errorf("%d lines across the files, want 40", lines)
+	}
+}
+
+func TestLoggerRotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	for i, name := range []string{path + ".1", path + ".2"} {
+		if err := os.WriteFile(name, []byte{byte('1' + i)}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log := engine.NewLoggerWithOptions(engine.LoggerOptions{Path: path, MaxSize: 200})
+	e := engine.NewEngineWithLogger(&types.Config{}, log, 1000, 0)
+	defer e.Close()
+	sub := e.Events.Subscribe(64, engine.DropOldest)
+
+	// Removing the file makes renaming it fail; the open view still shows
+	// what the logger appends to it
+	e.ReloadFailed(errors.New("before"))
+	view, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Close()
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		e.ReloadFailed(errors.New(strings.Repeat("x", 80)))
+	}
+	e.ReloadFailed(errors.New("after"))
+
+	reported := false
+	for len(sub.C) > 0 {
+		if ev := <-sub.C; ev.Type == engine.EventError && strings.Contains(ev.Text, "Log rotation failed") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Error("no error event for the failed rotation")
+	}
+	if !strings.Contains(log.ReadAll(), "Log rotation failed") {
+		t.Errorf("failed rotation not in the log:\n%s", log.ReadAll())
+	}
+
+	log.Close()
+	data, err := io.ReadAll(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "after") {
+		t.Errorf("logging stopped after the failed rotation:\n%s", data)
+	}
+	// The backups were not shifted for a rotation that did not happen
+	for i, name := range []string{path + ".1", path + ".2"} {
+		if data, err := os.ReadFile(name); err != nil || string(data) != string(rune('1'+i)) {
+			t.Errorf("%s holds %q (%v), want it untouched", filepath.Base(name), data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("backups shifted by the failed rotation")
+	}
+}
+
+func TestLoggerEntries(t *testing.T) {
+	log := engine.NewLoggerWithOptions(engine.LoggerOptions{Lines: 2})
+	defer log.Close()
+	for _, msg := range []string{"one", "two", "three"} {
+		log.Write(msg)
+	}
+
+	entries := log.Entries()
+	if len(entries) != 2 || entries[0].Text != "two" || entries[1].Text != "three" {
+		t.Fatalf("entries = %+v, want the last two lines oldest first", entries)
+	}
+	if e := entries[0]; e.Endpoint != -1 || e.Level != engine.LevelInfo {
+		t.Errorf("plain line has endpoint %d and level %v", e.Endpoint, e.Level)
+	}
+}
+
+// With dumps on, received payloads are logged at the default level like sent
+// ones, so both directions show without log_level = "debug".
+func TestLoggerDumpsBothDirections(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(c, buf); err == nil {
+			c.Write([]byte("world"))
+		}
+		io.Copy(io.Discard, c)
+	}()
+
+	cfg := &types.Config{
+		Globals: types.Globals{Timeout: 1000},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port},
+			{ID: 2, Kind: "client", Address: "127.0.0.1"},
+		},
+		// The last message keeps the connection open for the reply
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "syn"},
+			{From: 2, To: 1, Kind: "data", Value: "68656c6c6f"},
+			{From: 2, To: 1, Kind: "data", Value: "00", TDelta: 200},
+		},
+	}
+	log := engine.NewLoggerWithOptions(engine.LoggerOptions{Level: engine.LevelInfo, Dumps: true})
+	e := engine.NewEngineWithLogger(cfg, log, 1000, 0)
+	defer e.Close()
+
+	e.StartEndpoint(2)
+	waitStatus(t, e, types.StatusCompleted, 2)
+
+	all := log.ReadAll()
+	for _, want := range []string{"Sent 5 bytes 2 -> 1", "|hello|", "Received 5 bytes on endpoint 2", "|world|"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q at the default level:\n%s", want, all)
+		}
+	}
+	for _, entry := range log.Entries() {
+		if strings.HasPrefix(entry.Text, "Received") && entry.Level != engine.LevelInfo {
+			t.Errorf("%q logged at %v, want info", entry.Text, entry.Level)
+		}
+	}
+}
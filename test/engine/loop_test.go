@@ -0,0 +1,107 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestStopDuringThinkTime(t *testing.T) {
+	cfg := &types.Config{
+		Globals: types.Globals{Iterations: 3, ThinkTime: 10000},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: 9},
+			{ID: 2, Kind: "client", Address: "127.0.0.1", Port: 9},
+			{ID: 3, Kind: "client", Address: "127.0.0.1", Port: 9},
+		},
+		// Sent without a connection, so the iteration ends at once
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "data", Value: "01"},
+			{From: 3, To: 1, Kind: "data", Value: "01"},
+		},
+	}
+	e := engine.NewEngine(cfg, "", 100, 1000, 0)
+	defer e.Close()
+	defer e.StopAll()
+
+	e.StartEndpoint(2)
+	e.StartEndpoint(3)
+	time.Sleep(100 * time.Millisecond)
+	e.StopEndpoint(2)
+
+	// Give the run of 2 time to notice the stop and return
+	time.Sleep(200 * time.Millisecond)
+	if st := e.GetStatus(2); st != types.StatusIdle {
+		t.Errorf("stopped endpoint is %v, want %v", st, types.StatusIdle)
+	}
+	if st := e.GetStatus(3); st != types.StatusRunning {
+		t.Errorf("other endpoint is %v, want %v", st, types.StatusRunning)
+	}
+	e.Mutex.Lock()
+	running := e.Running
+	e.Mutex.Unlock()
+	if !running {
+		t.Error("engine not running while endpoint 3 still is")
+	}
+	if log := e.Log.ReadAll(); strings.Contains(log, "Endpoint 2 finished trace") {
+		t.Errorf("stopped endpoint logged as finished:\n%s", log)
+	}
+}
+
+func TestRestartCountsOnce(t *testing.T) {
+	cfg := &types.Config{
+		Globals: types.Globals{Iterations: 3, ThinkTime: 10000},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: 9},
+			{ID: 2, Kind: "client", Address: "127.0.0.1", Port: 9},
+			{ID: 3, Kind: "client", Address: "127.0.0.1", Port: 9},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "data", Value: "01"},
+			{From: 3, To: 1, Kind: "data", Value: "01"},
+		},
+	}
+	e := engine.NewEngine(cfg, "", 100, 1000, 0)
+	defer e.Close()
+	defer e.StopAll()
+
+	e.StartEndpoint(2)
+	e.StartEndpoint(3)
+	time.Sleep(100 * time.Millisecond)
+	e.RestartEndpoint(2)
+	time.Sleep(200 * time.Millisecond)
+
+	if st := e.GetStatus(2); st != types.StatusRunning {
+		t.Errorf("restarted endpoint is %v, want %v", st, types.StatusRunning)
+	}
+	// The restarted endpoint keeps the engine running on its own
+	e.StopEndpoint(3)
+	e.Mutex.Lock()
+	running := e.Running
+	e.Mutex.Unlock()
+	if !running {
+		t.Error("engine not running while the restarted endpoint still is")
+	}
+}
+
+func TestClientWithoutMessages(t *testing.T) {
+	cfg := &types.Config{
+		Globals:   types.Globals{Duration: 300},
+		Endpoints: []types.Endpoint{{ID: 1, Kind: "client", Address: "127.0.0.1", Port: 9}},
+	}
+	e := engine.NewEngine(cfg, "", 1000, 1000, 0)
+	defer e.Close()
+
+	e.StartEndpoint(1)
+	waitStatus(t, e, types.StatusCompleted, 1)
+	log := e.Log.ReadAll()
+	if n := strings.Count(log, "iteration"); n != 0 {
+		t.Errorf("logged %d iterations of an empty trace", n)
+	}
+	if !strings.Contains(log, "no messages") {
+		t.Errorf("no warning about the empty trace:\n%s", log)
+	}
+}
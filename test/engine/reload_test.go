@@ -0,0 +1,80 @@
+package engine_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+func reloadScenario() *types.Config {
+	return &types.Config{
+		Globals: types.Globals{Timeout: 1000},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: 0},
+			{ID: 2, Kind: "server", Address: "127.0.0.1", Port: 0},
+			{ID: 3, Kind: "client", Address: "127.0.0.1", Port: 0},
+		},
+		Messages: []types.Message{
+			{From: 3, To: 1, Kind: "data", Value: "01"},
+			{From: 1, To: 3, Kind: "data", Value: "02"},
+		},
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*types.Config)
+		globals bool
+		added   []int
+		removed []int
+		changed []int
+	}{
+		{"unchanged", func(*types.Config) {}, false, nil, nil, nil},
+		{"globals", func(c *types.Config) { c.Globals.Timeout = 5 }, true, nil, nil, nil},
+		{"message value", func(c *types.Config) { c.Messages[1].Value = "03" }, false, nil, nil, []int{1}},
+		{"server port moves its clients", func(c *types.Config) { c.Endpoints[0].Port = 9 }, false, nil, nil, []int{1, 3}},
+		{"added and removed", func(c *types.Config) {
+			c.Endpoints[1] = types.Endpoint{ID: 4, Kind: "server", Address: "127.0.0.1"}
+		}, false, []int{4}, []int{2}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := reloadScenario()
+			tt.edit(cfg)
+			d := engine.DiffConfigs(reloadScenario(), cfg)
+			if d.Globals != tt.globals || !slices.Equal(d.Added, tt.added) ||
+				!slices.Equal(d.Removed, tt.removed) || !slices.Equal(d.Changed, tt.changed) {
+				t.Errorf("got %+v", d)
+			}
+			if d.Empty() != (tt.name == "unchanged") {
+				t.Errorf("Empty() = %v", d.Empty())
+			}
+		})
+	}
+}
+
+func TestReloadKeepsUnaffectedEndpoints(t *testing.T) {
+	e := engine.NewEngine(reloadScenario(), "", 100, 1000, 0)
+	defer e.Close()
+	e.StartEndpoint(1)
+	e.StartEndpoint(2)
+	waitStatus(t, e, types.StatusRunning, 1, 2)
+
+	cfg := reloadScenario()
+	cfg.Messages[1].Value = "03"
+	d, ok := e.Reload(cfg)
+	if !ok || !slices.Equal(d.Changed, []int{1}) {
+		t.Fatalf("Reload = %+v, %v", d, ok)
+	}
+	// The changed server is restarted, the other one kept
+	waitStatus(t, e, types.StatusRunning, 1, 2)
+
+	cfg = reloadScenario()
+	cfg.Globals.Timeout = 5
+	if _, ok := e.Reload(cfg); ok {
+		t.Error("Reload accepted changed globals")
+	}
+}
@@ -0,0 +1,56 @@
+package engine_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestRestartEndpoint(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := &types.Config{Endpoints: []types.Endpoint{
+		{ID: 1, Kind: "server", Address: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port},
+	}}
+	e := engine.NewEngine(cfg, "", 100, 1000, 0)
+	defer e.Close()
+
+	// The new run can only listen once the old one has let go of the port
+	dial := func() error {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			c, err := net.Dial("tcp", addr)
+			if err == nil {
+				c.Close()
+				return nil
+			}
+			if time.Now().After(deadline) {
+				return err
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	e.StartEndpoint(1)
+	if err := dial(); err != nil {
+		t.Fatalf("server not listening: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		e.RestartEndpoint(1)
+		time.Sleep(50 * time.Millisecond)
+		if err := dial(); err != nil {
+			t.Fatalf("restart %d: server not listening: %v", i, err)
+		}
+		if st := e.GetStatus(1); st != types.StatusRunning {
+			t.Fatalf("restart %d: status %v, want running", i, st)
+		}
+	}
+}
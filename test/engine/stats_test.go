@@ -0,0 +1,89 @@
+package engine_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	samples := []time.Duration{ms(5), ms(1), ms(4), ms(2), ms(3)}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, ms(1)},
+		{50, ms(3)},
+		{100, ms(5)},
+	}
+	for _, tt := range tests {
+		if got := engine.Percentile(samples, tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := engine.Percentile(nil, 99); got != 0 {
+		t.Errorf("Percentile of no samples = %v, want 0", got)
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	// An echo server answers each write, so every message has a response
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(c, c)
+				c.Close()
+			}()
+		}
+	}()
+
+	cfg := &types.Config{
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port},
+			{ID: 2, Kind: "client", Address: "127.0.0.1"},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "syn"},
+			{From: 2, To: 1, Kind: "data", Value: "6869"},
+			{From: 2, To: 1, Kind: "data", Value: "6869", TDelta: 20},
+			{From: 2, To: 1, Kind: "fin", TDelta: 20},
+		},
+	}
+	e := engine.NewEngine(cfg, "", 100, 1000, 0)
+	defer e.Close()
+
+	e.StartEndpoint(2)
+	deadline := time.Now().Add(2 * time.Second)
+	for e.GetStatus(2) != types.StatusCompleted || e.Stats().Connections > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not finish: status %v, stats %+v", e.GetStatus(2), e.Stats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	st := e.Stats()
+	if st.Messages != 4 || st.BytesSent != 4 || st.Errors != 0 {
+		t.Errorf("stats = %+v, want 4 messages, 4 bytes sent, no errors", st)
+	}
+	if len(st.Latencies) == 0 {
+		t.Error("no response latencies recorded")
+	}
+	if p := st.Progress[2]; p.Message != 4 || p.Total != 4 {
+		t.Errorf("progress of endpoint 2 = %+v, want 4/4", p)
+	}
+}
@@ -0,0 +1,123 @@
+package engine_test
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+// silentPeer listens for connections it never reads from or writes to, with
+// a small receive buffer so large writes stall. It returns the port.
+func silentPeer(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.(*net.TCPConn).SetReadBuffer(4096)
+			mu.Lock()
+			conns = append(conns, c)
+			mu.Unlock()
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range conns {
+			c.Close()
+		}
+	})
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// firstTimeout runs client 2 of cfg, whose engine defaults to a 300ms
+// connect timeout, and returns the first timeout it reports.
+func firstTimeout(t *testing.T, cfg *types.Config) *engine.TimeoutError {
+	t.Helper()
+	e := engine.NewEngine(cfg, "", 100, 300, 0)
+	defer e.Close()
+	defer e.StopAll()
+	sub := e.Events.Subscribe(256, engine.DropOldest)
+
+	e.StartEndpoint(2)
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-sub.C:
+			var terr *engine.TimeoutError
+			if errors.As(ev.Err, &terr) {
+				return terr
+			}
+		case <-deadline:
+			t.Fatal("no timeout reported")
+			return nil
+		}
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	// Enough to fill the socket buffers of both ends
+	large := strings.Repeat("00", 16<<20)
+
+	tests := []struct {
+		name    string
+		globals types.Globals
+		client  types.Endpoint
+		payload string
+		op      string
+		want    time.Duration
+	}{
+		{"read from globals", types.Globals{ReadTimeout: 150}, types.Endpoint{}, "01", "read", 150 * time.Millisecond},
+		{"read from endpoint", types.Globals{ReadTimeout: 5000}, types.Endpoint{ReadTimeout: 150}, "01", "read", 150 * time.Millisecond},
+		{"idle from endpoint", types.Globals{IdleTimeout: 5000}, types.Endpoint{IdleTimeout: 150}, "01", "idle", 150 * time.Millisecond},
+		{"write from endpoint", types.Globals{WriteTimeout: 5000}, types.Endpoint{WriteTimeout: 150}, large, "write", 150 * time.Millisecond},
+		{"write falls back to connect", types.Globals{}, types.Endpoint{Timeout: 200}, large, "write", 200 * time.Millisecond},
+		{"write falls back to the default", types.Globals{}, types.Endpoint{}, large, "write", 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.client
+			client.ID, client.Kind, client.Address = 2, "client", "127.0.0.1"
+			cfg := &types.Config{
+				Globals: tt.globals,
+				Endpoints: []types.Endpoint{
+					{ID: 1, Kind: "server", Address: "127.0.0.1", Port: silentPeer(t)},
+					client,
+				},
+				Messages: []types.Message{
+					{From: 2, To: 1, Kind: "syn"},
+					{From: 2, To: 1, Kind: "data", Value: tt.payload},
+				},
+			}
+
+			start := time.Now()
+			terr := firstTimeout(t, cfg)
+			if terr.Op != tt.op || terr.Timeout != tt.want {
+				t.Errorf("got %s timeout of %v, want %s of %v", terr.Op, terr.Timeout, tt.op, tt.want)
+			}
+			if terr.Endpoint != 2 || terr.Peer != 1 {
+				t.Errorf("timeout on %d -> %d, want 2 -> 1", terr.Endpoint, terr.Peer)
+			}
+			if elapsed := time.Since(start); elapsed < tt.want {
+				t.Errorf("fired after %v, before its %v", elapsed, tt.want)
+			}
+		})
+	}
+}
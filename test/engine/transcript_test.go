@@ -0,0 +1,53 @@
+package engine_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+// A new run, like one after a reload, adds transcripts next to those of
+// the earlier runs.
+func TestTranscriptsKeepEarlierRuns(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &types.Config{
+		Globals: types.Globals{Timeout: 1000},
+		Endpoints: []types.Endpoint{
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: freePort(t, "127.0.0.1")},
+			{ID: 2, Kind: "client", Address: "127.0.0.1", Port: 0},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 1, Kind: "syn"},
+			{From: 2, To: 1, Kind: "data", Value: "01"},
+		},
+	}
+
+	for run := 1; run <= 2; run++ {
+		e := engine.NewEngine(cfg, "", 100, 1000, 0)
+		e.EnableTranscripts(dir)
+		e.StartEndpoint(1)
+		time.Sleep(50 * time.Millisecond)
+		e.StartEndpoint(2)
+		waitStatus(t, e, types.StatusCompleted, 2)
+		time.Sleep(50 * time.Millisecond)
+		e.StopAll()
+		e.Close()
+
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 2*run {
+			t.Fatalf("after run %d: %d transcripts, want %d", run, len(files), 2*run)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "ep2-to1-*.txt"))
+	if len(matches) != 2 {
+		t.Errorf("client transcripts %v, want one per run", matches)
+	}
+}
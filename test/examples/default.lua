@@ -25,7 +25,7 @@ config.endpoints = {
 		id = 2,
 		kind = "server",
 		address = "127.0.0.1",
-		port = 9990,
+		port = 9991,
 	},
 	{
 		id = 4,
@@ -0,0 +1,127 @@
+package lua_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/types"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIncludeAndExtends(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"shared/topology.lua": `return {
+			endpoints = { { id = 1, kind = "server", address = "10.0.0.1", port = 80 } },
+		}`,
+		"lib/payloads.lua": `return { hello = "68656c6c6f" }`,
+		"base.lua": `return {
+			globals = { protocol = "tcp", play_mode = "pcap", timeout = 100 },
+			include = "shared/topology.lua",
+			endpoints = { { id = 0, kind = "client", address = "127.0.0.1" } },
+			messages = { { from = 0, to = 1, kind = "syn" } },
+		}`,
+		"web/staging.lua": `local payloads = require("lib.payloads")
+		return {
+			extends = "../base.lua",
+			globals = { timeout = 500 },
+			endpoints = { { id = 0, kind = "client", address = "192.168.1.5" } },
+			messages = {
+				{ from = 0, to = 1, kind = "syn" },
+				{ from = 0, to = 1, kind = "data", value = payloads.hello },
+			},
+		}`,
+		"web/lib/payloads.lua": `return { hello = "6869" }`,
+	})
+
+	cfg, err := lua.ReadLuaConfigWithOptions(context.Background(), filepath.Join(dir, "web", "staging.lua"), lua.Options{Sandbox: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g := cfg.Globals; g.Protocol != "tcp" || g.Timeout != 500 {
+		t.Errorf("globals %+v: want protocol from base and timeout from override", g)
+	}
+	want := []types.Endpoint{
+		{ID: 1, Kind: "server", Address: "10.0.0.1", Port: 80},
+		{ID: 0, Kind: "client", Address: "192.168.1.5"},
+	}
+	if len(cfg.Endpoints) != len(want) {
+		t.Fatalf("endpoints %+v, want %+v", cfg.Endpoints, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(cfg.Endpoints[i], want[i]) {
+			t.Errorf("endpoint %d: %+v, want %+v", i, cfg.Endpoints[i], want[i])
+		}
+	}
+	if len(cfg.Messages) != 2 || cfg.Messages[1].Value != "6869" {
+		t.Errorf("messages %+v: want the override's, with the payload required next to it", cfg.Messages)
+	}
+	if len(cfg.Sources) != 4 {
+		t.Errorf("sources %v: want the scenario, its module, base and topology", cfg.Sources)
+	}
+}
+
+func TestRequireSources(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib/topo.lua": `return { { id = 1, kind = "server", address = "10.0.0.1", port = 80 } }`,
+		"main.lua": `local topo = require("lib.topo")
+		return { endpoints = topo }`,
+	})
+	main := filepath.Join(dir, "main.lua")
+	want := []string{main, filepath.Join(dir, "lib", "topo.lua")}
+
+	for _, sandbox := range []bool{true, false} {
+		cfg, err := lua.ReadLuaConfigWithOptions(context.Background(), main, lua.Options{Sandbox: sandbox})
+		if err != nil {
+			t.Fatalf("sandbox %v: %v", sandbox, err)
+		}
+		if !reflect.DeepEqual(cfg.Sources, want) {
+			t.Errorf("sandbox %v: sources %v, want %v", sandbox, cfg.Sources, want)
+		}
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.lua":     `return { endpoints = { { id = 1, kind = "server", port = 80 } } }`,
+		"b.lua":     `return { endpoints = { { id = 1, kind = "server", port = 81 } } }`,
+		"both.lua":  `return { include = { "a.lua", "b.lua" } }`,
+		"own.lua":   `return { include = "a.lua", endpoints = { { id = 1, kind = "client" } } }`,
+		"loop1.lua": `return { extends = "loop2.lua" }`,
+		"loop2.lua": `return { include = "loop1.lua" }`,
+		"escape.lua": `require("..secrets")
+		return {}`,
+	})
+
+	tests := map[string]string{
+		"both.lua":   "endpoint 1 is also defined in",
+		"own.lua":    "endpoint 1 is also defined in",
+		"loop1.lua":  "include cycle",
+		"escape.lua": "inside the scenario directory",
+	}
+	for name, want := range tests {
+		_, err := lua.ReadLuaConfigWithOptions(context.Background(), filepath.Join(dir, name), lua.Options{Sandbox: true})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want an error containing %q", name, err, want)
+		}
+	}
+}
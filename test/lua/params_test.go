@@ -0,0 +1,64 @@
+package lua_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/lua"
+)
+
+const paramScenario = `local p = declare_params {
+	host  = "127.0.0.1",
+	port  = { type = "integer", default = 8080 },
+	token = { type = "string", required = true, description = "API token" },
+}
+return {
+	endpoints = {
+		{ id = 0, kind = "client", address = "127.0.0.1" },
+		{ id = 1, kind = "server", address = p.host, port = p.port },
+	},
+	messages = { { from = 0, to = 1, kind = "data", value = p.token } },
+}`
+
+func TestParams(t *testing.T) {
+	path := writeScript(t, paramScenario)
+	read := func(params map[string]string) error {
+		_, err := lua.ReadLuaConfigWithOptions(context.Background(), path, lua.Options{Sandbox: true, Params: params})
+		return err
+	}
+
+	t.Setenv(lua.ParamEnvPrefix+"HOST", "10.0.0.9")
+	cfg, err := lua.ReadLuaConfigWithOptions(context.Background(), path,
+		lua.Options{Sandbox: true, Params: map[string]string{"token": "abcd", "port": "9000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep := cfg.Endpoints[1]; ep.Address != "10.0.0.9" || ep.Port != 9000 || cfg.Messages[0].Value != "abcd" {
+		t.Errorf("got server %s:%d value %q, want env host, -p port and token", ep.Address, ep.Port, cfg.Messages[0].Value)
+	}
+
+	var perr *lua.ParamsError
+	if err := read(nil); !errors.As(err, &perr) || len(perr.Missing) != 1 || perr.Missing[0] != "token" {
+		t.Errorf("no token: got %v, want missing token", err)
+	}
+	if err := read(map[string]string{"token": "ab", "port": "http"}); err == nil || !strings.Contains(err.Error(), "not a valid integer") {
+		t.Errorf("bad port: got %v", err)
+	}
+	if err := read(map[string]string{"token": "ab", "prot": "1"}); err == nil || !strings.Contains(err.Error(), "unknown params prot") {
+		t.Errorf("misspelled param: got %v", err)
+	}
+
+	params, err := lua.DeclaredParams(path, lua.Options{Sandbox: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range params {
+		names = append(names, p.Name+"="+p.Value)
+	}
+	if got := strings.Join(names, " "); got != "host=10.0.0.9 port=8080 token=" {
+		t.Errorf("declared %s", got)
+	}
+}
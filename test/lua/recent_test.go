@@ -0,0 +1,76 @@
+package lua_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/lua"
+)
+
+func TestRecentIndex(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a_1.lua", "b_1.lua", "manual.lua"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("return {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "manual.lua"), old, old)
+
+	now := time.Now()
+	record := func(name string, imported time.Time) {
+		err := lua.UpdateRecent(dir, name, func(e *lua.RecentEntry) {
+			e.Source, e.Imported, e.Endpoints, e.Messages = "/captures/"+name, imported, 2, 5
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("a_1.lua", now.Add(-time.Minute))
+	record("b_1.lua", now)
+
+	names := func() []string {
+		entries, err := lua.ListRecent(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		return names
+	}
+	if got := names(); len(got) != 3 || got[0] != "b_1.lua" || got[1] != "a_1.lua" || got[2] != "manual.lua" {
+		t.Fatalf("listed %v, want newest first and the unindexed file by its modification time", got)
+	}
+
+	// Pinned scenarios come first; renaming keeps the entry
+	if err := lua.UpdateRecent(dir, "manual.lua", func(e *lua.RecentEntry) { e.Pinned = true }); err != nil {
+		t.Fatal(err)
+	}
+	if err := lua.RenameRecent(dir, "a_1.lua", "login"); err != nil {
+		t.Fatal(err)
+	}
+	if err := lua.RenameRecent(dir, "b_1.lua", "login"); err == nil {
+		t.Error("renaming onto an existing scenario succeeded")
+	}
+	if err := lua.DeleteRecent(dir, "b_1.lua"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := lua.ListRecent(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "manual.lua" || !entries[0].Pinned || entries[1].Name != "login.lua" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if e := entries[1]; e.Source != "/captures/a_1.lua" || e.Endpoints != 2 || e.Messages != 5 {
+		t.Errorf("renamed scenario lost its entry: %+v", e)
+	}
+	if !entries[0].Imported.Equal(old) {
+		t.Errorf("pinning redated an unindexed scenario to %v", entries[0].Imported)
+	}
+}
@@ -0,0 +1,70 @@
+package lua_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/lua"
+)
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.lua")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSandboxErrors(t *testing.T) {
+	opts := lua.Options{Sandbox: true, Timeout: 200 * time.Millisecond}
+
+	tests := []struct {
+		name string
+		src  string
+		line int
+		msg  string
+	}{
+		{"no io", "local config = {}\nio.open('x', 'w')\nreturn config", 2, "attempt to index a non-table object(nil)"},
+		{"no os.remove", "local config = {}\n\nos.remove('x')\nreturn config", 3, "attempt to call a non-function object"},
+		{"no dofile", "dofile('x')", 1, "attempt to call a non-function object"},
+		{"syntax", "local config = {}\nconfig.x = = 1", 2, "syntax error"},
+		{"runtime", "local config = {}\nerror('boom')", 2, "boom"},
+		{"timeout", "while true do end", 1, "time limit of 200ms"},
+		{"recursion", "local function f() return 1 + f() end\nf()", 1, "stack overflow"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeScript(t, tc.src)
+			_, err := lua.ReadLuaConfigWithOptions(context.Background(), path, opts)
+
+			var serr *lua.ScriptError
+			if !errors.As(err, &serr) {
+				t.Fatalf("got %v, want *ScriptError", err)
+			}
+			if serr.File != path || serr.Line != tc.line {
+				t.Errorf("position %s:%d, want %s:%d", serr.File, serr.Line, path, tc.line)
+			}
+			if !strings.Contains(serr.Msg, tc.msg) {
+				t.Errorf("message %q does not mention %q", serr.Msg, tc.msg)
+			}
+		})
+	}
+}
+
+func TestSandboxRunsExample(t *testing.T) {
+	opts := lua.Options{Sandbox: true, Timeout: time.Second, MemoryMB: 64}
+	cfg, err := lua.ReadLuaConfigWithOptions(context.Background(), "../examples/default.lua", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Messages) == 0 {
+		t.Error("example loaded without messages")
+	}
+}
@@ -0,0 +1,67 @@
+package lua_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/lua"
+)
+
+const remapScenario = `return {
+	globals = { remap = { "10.0.0.0/8 -> 127.0.0.3", "port 80 -> 8080" } },
+	endpoints = {
+		{ id = 0, kind = "server", address = "10.0.0.1", port = 80 },
+		{ id = 1, kind = "server", address = "10.0.0.2", port = 80 },
+		{ id = 2, kind = "client", address = "10.0.0.3" },
+	},
+	messages = {
+		{ from = 2, to = 0, kind = "syn" },
+		{ from = 2, to = 1, kind = "syn" },
+	},
+}`
+
+// Rules from the options come before the scenario's own, and validation
+// sees the remapped endpoints.
+func TestLoadScenarioRemaps(t *testing.T) {
+	path := writeScript(t, remapScenario)
+	ctx := context.Background()
+
+	cfg, err := lua.LoadScenario(ctx, path, lua.Options{Remap: []string{"10.0.0.1 -> 127.0.0.2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"127.0.0.2:8080", "127.0.0.3:8080"}
+	for i, w := range want {
+		ep := cfg.Endpoints[i]
+		if got := net.JoinHostPort(ep.Address, strconv.Itoa(ep.Port)); got != w {
+			t.Errorf("endpoint %d at %s, want %s", ep.ID, got, w)
+		}
+	}
+	if len(cfg.MessagesByFrom[2]) != 2 {
+		t.Error("messages not indexed")
+	}
+
+	// Without the option both servers end up on 127.0.0.3:8080
+	_, err = lua.LoadScenario(ctx, path, lua.Options{})
+	var verr *lua.ValidationError
+	if !errors.As(err, &verr) || !strings.Contains(err.Error(), "endpoint 1: listens on 127.0.0.3:8080 like endpoint 0") {
+		t.Errorf("got %v, want the remapped servers rejected", err)
+	}
+	problems, err := lua.LintFileWithOptions(ctx, path, lua.Options{})
+	if err != nil || len(problems) != 1 || problems[0].Endpoint != 1 {
+		t.Errorf("lint got %v (%v), want the same duplicate listener", problems, err)
+	}
+
+	// The scenario as written is untouched
+	raw, err := lua.ReadScenario(ctx, path, lua.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep := raw.Endpoints[0]; ep.Address != "10.0.0.1" || ep.Port != 80 {
+		t.Errorf("ReadScenario remapped endpoint 0 to %s:%d", ep.Address, ep.Port)
+	}
+}
@@ -0,0 +1,11 @@
+return {
+	endpoints = {
+		{ id = 0, kind = "server", address = "127.0.0.1", port = 9990 },
+		{ id = 1, kind = "client", address = "127.0.0.1" },
+		{ id = 2, kind = "server", address = "127.0.0.1", port = 9990 },
+	},
+	messages = {
+		{ from = 1, to = 0, kind = "syn" },
+		{ from = 1, to = 2, kind = "syn" },
+	},
+}
@@ -0,0 +1,90 @@
+local config = {}
+
+-- GLOBALS ---------------------------------------
+config.globals = {
+	protocol = "tcp",
+	play_mode = "pcap",
+	timeout = 3000,
+	delay = 50,
+	log_lines = 500,
+	read_timeout = 100,
+	write_timeout = 200,
+	idle_timeout = 300,
+	fin_drain = 400,
+	iterations = 3,
+	duration = 60000,
+	think_time = 250,
+	bind_source = true,
+	source_pool = { "127.0.0.2", "127.0.0.3" },
+	remap = { "10.0.0.0/8 -> 127.0.0.1", "port 80 -> 8080" },
+	load = {
+		endpoints = { 1 },
+		start_rate = 0.5,
+		max_sessions = 20,
+		stages = {
+			{
+				duration = 1000,
+				rate = 2.25,
+				shape = "ramp",
+			},
+			{
+				duration = 500,
+				rate = 10,
+				shape = "step",
+			},
+		},
+	},
+}
+
+-- ENDPOINTS -------------------------------------
+config.endpoints = {
+	{
+		id = 0,
+		kind = "server",
+		address = "say \"hi\"\tback\\slash\n\001",
+		port = 8080,
+	},
+	{
+		id = 1,
+		kind = "client",
+		address = "127.0.0.1",
+		port = 40000,
+		bind = true,
+		source_pool = { "127.0.0.4" },
+		timeout = 1,
+		read_timeout = 2,
+		write_timeout = 3,
+		idle_timeout = 4,
+		fin_drain = 5,
+		iterations = 6,
+		duration = 7,
+		think_time = 8,
+	},
+}
+
+-- MESSAGES --------------------------------------
+config.messages = {
+	{
+		from = 1,
+		to = 0,
+		kind = "syn",
+		value = "",
+		t_delta = 0,
+	},
+	{
+		from = 1,
+		to = 0,
+		kind = "data",
+		value = "474554202f0a",
+		t_delta = 15,
+	},
+	{
+		from = 1,
+		to = 0,
+		kind = "fin",
+		value = "",
+		t_delta = 1,
+	},
+}
+
+return config
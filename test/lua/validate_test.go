@@ -0,0 +1,84 @@
+package lua_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestValidateConfigReportsAllProblems(t *testing.T) {
+	cfg := &types.Config{
+		Globals: types.Globals{Protocol: "udp", PlayMode: "pcap"},
+		Endpoints: []types.Endpoint{
+			{ID: 0, Kind: "server", Address: "127.0.0.1", Port: 80},
+			{ID: 1, Kind: "server", Address: "127.0.0.1", Port: 80},
+			{ID: 1, Kind: "client", Address: "127.0.0.1"},
+			{ID: 2, Kind: "client", Address: "127.0.0.1", Port: 70000},
+			{ID: 3, Kind: "peer", Address: "127.0.0.1"},
+			{ID: 4, Kind: "client", Address: "127.0.0.1"},
+		},
+		Messages: []types.Message{
+			{From: 2, To: 0, Kind: "data", Value: "00"},
+			{From: 2, To: 0, Kind: "syn"},
+			{From: 2, To: 0, Kind: "data", Value: "zz"},
+			{From: 2, To: 0, Kind: "bogus", TDelta: -1},
+			{From: 2, To: 4, Kind: "syn"},
+			{From: 2, To: 9, Kind: "syn"},
+		},
+	}
+
+	err := lua.ValidateConfig(cfg)
+	var verr *lua.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want *ValidationError", err)
+	}
+
+	want := []string{
+		`globals: unsupported protocol "udp"`,
+		"endpoint 1: listens on 127.0.0.1:80 like endpoint 0",
+		"endpoint 1: duplicate endpoint id",
+		"endpoint 2: invalid port 70000",
+		`endpoint 3: unknown kind "peer"`,
+		"message 2: value is not hex",
+		`message 3: unknown kind "bogus"`,
+		"message 3: negative t_delta -1",
+		"message 4: between two clients (2 -> 4)",
+		"message 5: invalid to id 9",
+	}
+	got := err.Error()
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("missing %q in\n%s", w, got)
+		}
+	}
+	if len(verr.Problems) != len(want) {
+		t.Errorf("got %d problems, want %d: %s", len(verr.Problems), len(want), got)
+	}
+
+	// Data before a syn only warns, so it is linted but not rejected
+	var warned bool
+	for _, p := range lua.Lint(cfg) {
+		if p.Severity == lua.SeverityWarning && p.Message == 0 {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("data before syn not reported as a warning")
+	}
+}
+
+func TestReadRejectsDuplicateListener(t *testing.T) {
+	_, err := lua.ReadLuaConfig(filepath.Join("testdata", "duplicate_listener.lua"))
+	var verr *lua.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want *ValidationError", err)
+	}
+	want := "endpoint 2: listens on 127.0.0.1:9990 like endpoint 0"
+	if len(verr.Problems) != 1 || verr.Problems[0].String() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
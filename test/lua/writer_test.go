@@ -0,0 +1,176 @@
+package lua_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/types"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// fullConfig sets every field, so a field the writer misses fails the
+// round trip.
+func fullConfig() *types.Config {
+	return &types.Config{
+		Globals: types.Globals{
+			Protocol: "tcp", PlayMode: "pcap", Timeout: 3000, Delay: 50, LogLines: 500,
+			ReadTimeout: 100, WriteTimeout: 200, IdleTimeout: 300, FinDrain: 400,
+			Iterations: 3, Duration: 60000, ThinkTime: 250,
+			BindSource: true,
+			SourcePool: []string{"127.0.0.2", "127.0.0.3"},
+			Remap:      []string{"10.0.0.0/8 -> 127.0.0.1", "port 80 -> 8080"},
+			Load: types.LoadProfile{
+				Endpoints:   []int{1},
+				StartRate:   0.5,
+				MaxSessions: 20,
+				Stages: []types.LoadStage{
+					{Duration: 1000, Rate: 2.25, Shape: "ramp"},
+					{Duration: 500, Rate: 10, Shape: "step"},
+				},
+			},
+		},
+		Endpoints: []types.Endpoint{
+			{ID: 0, Kind: "server", Address: "say \"hi\"\tback\\slash\n\x01", Port: 8080},
+			{
+				ID: 1, Kind: "client", Address: "127.0.0.1", Port: 40000,
+				Bind: true, SourcePool: []string{"127.0.0.4"},
+				Timeout: 1, ReadTimeout: 2, WriteTimeout: 3, IdleTimeout: 4, FinDrain: 5,
+				Iterations: 6, Duration: 7, ThinkTime: 8,
+			},
+		},
+		Messages: []types.Message{
+			{From: 1, To: 0, Kind: "syn"},
+			{From: 1, To: 0, Kind: "data", Value: "474554202f0a", TDelta: 15},
+			{From: 1, To: 0, Kind: "fin", TDelta: 1},
+		},
+	}
+}
+
+// assertAllSet fails for any zero field reachable from v, so fullConfig
+// has to grow with types.Config.
+func assertAllSet(t *testing.T, v reflect.Value, path string) {
+	t.Helper()
+	switch v.Kind() {
+	case reflect.Struct:
+		covered := false
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.Tag.Get("lua") == "-" {
+				continue
+			}
+			if !v.Field(i).IsZero() {
+				covered = true
+			}
+			assertAllSet(t, v.Field(i), path+"."+f.Name)
+		}
+		if !covered {
+			t.Errorf("%s: no field set in fullConfig", path)
+		}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			t.Errorf("%s: empty in fullConfig", path)
+		}
+	}
+}
+
+func TestWriteConfigRoundTrip(t *testing.T) {
+	cfg := fullConfig()
+	assertAllSet(t, reflect.ValueOf(cfg.Globals), "Globals")
+	assertAllSet(t, reflect.ValueOf(cfg.Endpoints[1]), "Endpoints[1]")
+
+	var buf bytes.Buffer
+	if err := lua.WriteConfig(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "full.lua")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output differs from %s (run with -update to accept):\n%s", golden, buf.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "full.lua")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := lua.ReadLuaConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.IndexMessages()
+	got.Sources = nil // Where got was read from, not part of the scenario
+	if !reflect.DeepEqual(got, cfg) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, cfg)
+	}
+}
+
+func TestWriteConfigExample(t *testing.T) {
+	cfg, err := lua.ReadLuaConfig("../examples/default.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "default.lua")
+	if err := lua.WriteFile(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := lua.ReadLuaConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Sources, cfg.Sources = nil, nil
+	if !reflect.DeepEqual(got, cfg) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, cfg)
+	}
+}
+
+func TestWriteFileKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.lua")
+	if err := os.WriteFile(path, []byte("return {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The umask may have narrowed the mode WriteFile was given
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &types.Config{Globals: types.Globals{Protocol: "tcp", Timeout: 1000}}
+	if err := lua.WriteFile(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0644 {
+		t.Errorf("rewritten file has mode %o, want 644", mode)
+	}
+}
+
+func TestWriteConfigOmitsUnsetLoop(t *testing.T) {
+	cfg := &types.Config{Globals: types.Globals{Protocol: "tcp", Timeout: 1000}}
+	var buf bytes.Buffer
+	if err := lua.WriteConfig(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"iterations", "duration", "think_time"} {
+		if strings.Contains(buf.String(), key) {
+			t.Errorf("unset %s written:\n%s", key, buf.String())
+		}
+	}
+}
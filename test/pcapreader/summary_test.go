@@ -0,0 +1,96 @@
+package pcapreader_test
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/pcapreader"
+	"github.com/samaelod/nabu/pcapwriter"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestSummarize(t *testing.T) {
+	cfg := &types.Config{
+		Endpoints: []types.Endpoint{
+			{ID: 0, Kind: "client", Address: "10.0.0.1", Port: 40000},
+			{ID: 1, Kind: "server", Address: "10.0.0.2", Port: 80},
+			{ID: 2, Kind: "client", Address: "10.0.0.3", Port: 40001},
+		},
+		Messages: []types.Message{
+			{From: 0, To: 1, Kind: "syn"},
+			{From: 1, To: 0, Kind: "syn-ack", TDelta: 1},
+			{From: 0, To: 1, Kind: "data", Value: "474554202f0d0a", TDelta: 10},
+			{From: 1, To: 0, Kind: "data", Value: "4f4b", TDelta: 20},
+			{From: 2, To: 1, Kind: "syn", TDelta: 5},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "out.pcapng")
+	if err := pcapwriter.SaveConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := pcapreader.Summarize(context.Background(), path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Format != "pcapng" || s.LinkType != "Ethernet" || s.Packets != 5 {
+		t.Errorf("got %s %s with %d packets, want pcapng Ethernet with 5", s.Format, s.LinkType, s.Packets)
+	}
+	if s.Span() != 36*time.Millisecond {
+		t.Errorf("span = %v, want 36ms", s.Span())
+	}
+	if len(s.Protocols) != 1 || s.Protocols[0] != (pcapreader.ProtocolCount{Name: "TCP", Packets: 5}) {
+		t.Errorf("protocols = %+v", s.Protocols)
+	}
+	if len(s.Conversations) != 1 {
+		t.Fatalf("kept %d conversations, want the top one", len(s.Conversations))
+	}
+	if c := s.Conversations[0]; c.A != "10.0.0.1:40000" || c.B != "10.0.0.2:80" || c.Packets != 4 {
+		t.Errorf("top conversation = %+v", c)
+	}
+	if len(s.Endpoints) != 3 || s.Endpoints[0] != "10.0.0.1:40000" {
+		t.Errorf("endpoints = %v", s.Endpoints)
+	}
+
+	// Zero keeps every conversation, a negative count is rejected
+	if s, err = pcapreader.Summarize(context.Background(), path, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Conversations) != 2 {
+		t.Errorf("kept %d conversations with top 0, want all 2", len(s.Conversations))
+	}
+	if _, err := pcapreader.Summarize(context.Background(), path, -1); err == nil {
+		t.Error("no error for a negative top")
+	}
+}
+
+func TestSummarizeCancel(t *testing.T) {
+	// More packets than gopacket buffers, so nothing is read ahead
+	cfg := &types.Config{
+		Endpoints: []types.Endpoint{
+			{ID: 0, Kind: "client", Address: "10.0.0.1", Port: 40000},
+			{ID: 1, Kind: "server", Address: "10.0.0.2", Port: 80},
+		},
+	}
+	for range 1500 {
+		cfg.Messages = append(cfg.Messages, types.Message{From: 0, To: 1, Kind: "data", Value: "00"})
+	}
+	path := filepath.Join(t.TempDir(), "big.pcapng")
+	if err := pcapwriter.SaveConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pcapreader.Summarize(ctx, path, 1); err == nil {
+		t.Error("summarizing with a cancelled context succeeded")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("%d goroutines left running after a cancelled summary", n-goroutines)
+	}
+}
@@ -0,0 +1,101 @@
+package pcapwriter_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"github.com/samaelod/nabu/pcapreader"
+	"github.com/samaelod/nabu/pcapwriter"
+	"github.com/samaelod/nabu/types"
+)
+
+func scenario() *types.Config {
+	return &types.Config{
+		Endpoints: []types.Endpoint{
+			{ID: 0, Kind: "client", Address: "10.0.0.1", Port: 40000},
+			{ID: 1, Kind: "server", Address: "10.0.0.2", Port: 80},
+		},
+		Messages: []types.Message{
+			{From: 0, To: 1, Kind: "syn"},
+			{From: 1, To: 0, Kind: "syn-ack", TDelta: 1},
+			{From: 0, To: 1, Kind: "ack", TDelta: 1},
+			{From: 0, To: 1, Kind: "data", Value: "474554202f0d0a", TDelta: 10},
+			{From: 1, To: 0, Kind: "data", Value: "4f4b", TDelta: 20},
+			{From: 0, To: 1, Kind: "fin", TDelta: 5},
+		},
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pcapwriter.WriteConfig(&buf, scenario()); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := pcapgo.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var segs []*layers.TCP
+	var stamps []time.Time
+	src := gopacket.NewPacketSource(r, r.LinkType())
+	for p := range src.Packets() {
+		if p.ErrorLayer() != nil {
+			t.Fatalf("decode error: %v", p.ErrorLayer().Error())
+		}
+		segs = append(segs, p.Layer(layers.LayerTypeTCP).(*layers.TCP))
+		stamps = append(stamps, p.Metadata().Timestamp)
+	}
+	if len(segs) != 6 {
+		t.Fatalf("got %d packets, want 6", len(segs))
+	}
+
+	syn, synAck, ack, req, resp, fin := segs[0], segs[1], segs[2], segs[3], segs[4], segs[5]
+	if !syn.SYN || syn.ACK || !synAck.SYN || !synAck.ACK || ack.SYN || !ack.ACK {
+		t.Errorf("handshake flags wrong")
+	}
+	if synAck.Ack != syn.Seq+1 || ack.Seq != syn.Seq+1 || ack.Ack != synAck.Seq+1 {
+		t.Errorf("handshake numbers: syn %d, syn-ack %d/%d, ack %d/%d",
+			syn.Seq, synAck.Seq, synAck.Ack, ack.Seq, ack.Ack)
+	}
+	if req.Seq != ack.Seq || resp.Ack != req.Seq+uint32(len(req.Payload)) {
+		t.Errorf("request seq %d len %d, response ack %d", req.Seq, len(req.Payload), resp.Ack)
+	}
+	if fin.Seq != req.Seq+uint32(len(req.Payload)) || fin.Ack != resp.Seq+uint32(len(resp.Payload)) {
+		t.Errorf("fin seq %d ack %d out of step", fin.Seq, fin.Ack)
+	}
+	if string(resp.Payload) != "OK" {
+		t.Errorf("response payload %q", resp.Payload)
+	}
+	if d := stamps[5].Sub(stamps[0]); d != 37*time.Millisecond {
+		t.Errorf("capture spans %v, want cumulative TDelta 37ms", d)
+	}
+}
+
+func TestSaveConfigRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.pcapng")
+	want := scenario()
+	if err := pcapwriter.SaveConfig(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pcapreader.ReadPCAP(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != len(want.Messages) {
+		t.Fatalf("got %d messages, want %d", len(got.Messages), len(want.Messages))
+	}
+	for i, m := range want.Messages {
+		if got.Messages[i] != m {
+			t.Errorf("message %d: got %+v, want %+v", i, got.Messages[i], m)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package remap_test
+
+import (
+	"testing"
+
+	"github.com/samaelod/nabu/remap"
+	"github.com/samaelod/nabu/types"
+)
+
+func TestApply(t *testing.T) {
+	rules, err := remap.ParseAll([]string{
+		"endpoint 3 -> 192.168.1.1:9000",
+		"10.1.0.5:443 -> 127.0.0.2:8443",
+		"10.1.0.0/16 -> 127.0.0.1",
+		"port 443 -> 8443",
+	})
+	if err != nil {
+		t.Fatalf("ParseAll: %v", err)
+	}
+
+	cfg := &types.Config{Endpoints: []types.Endpoint{
+		{ID: 0, Address: "10.1.2.3", Port: 443},
+		{ID: 1, Address: "10.1.0.5", Port: 443},
+		{ID: 2, Address: "10.2.0.1", Port: 443},
+		{ID: 3, Address: "10.1.2.3", Port: 80},
+		{ID: 4, Address: "10.2.0.1", Port: 80},
+	}}
+
+	if n := remap.Apply(cfg, rules); n != 4 {
+		t.Errorf("Apply changed %d endpoints, want 4", n)
+	}
+
+	want := []struct {
+		addr string
+		port int
+	}{
+		{"127.0.0.1", 8443},
+		{"127.0.0.2", 8443},
+		{"10.2.0.1", 8443},
+		{"192.168.1.1", 9000},
+		{"10.2.0.1", 80},
+	}
+	for i, w := range want {
+		ep := cfg.Endpoints[i]
+		if ep.Address != w.addr || ep.Port != w.port {
+			t.Errorf("endpoint %d = %s:%d, want %s:%d", ep.ID, ep.Address, ep.Port, w.addr, w.port)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"10.0.0.0/8",
+		"port 443 -> http",
+		"port 0 -> 80",
+		"endpoint x -> 127.0.0.1",
+		"not-an-ip -> 127.0.0.1",
+		" -> 127.0.0.1",
+	} {
+		if _, err := remap.Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package tui_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/tui"
+)
+
+// Removing endpoints changes the running scenario but not its file.
+func TestRemoveEndpointsKeepsFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("scenario.lua", []byte(watchScenario), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newDriver(t, tui.New("test", tui.Options{Scenario: "scenario.lua"}))
+	d.waitFor("127.0.0.1:9001", 1)
+	d.send(key("x"))
+	d.waitFor("from this session?", 1)
+	d.send(key("x"))
+	d.waitFor("- endpoint 0 (server 127.0.0.1:9001)", 1)
+
+	if strings.Contains(d.m.View(), "[0] 127.0.0.1:9001") {
+		t.Errorf("removed server still listed:\n%s", d.m.View())
+	}
+	if data, err := os.ReadFile("scenario.lua"); err != nil || string(data) != watchScenario {
+		t.Errorf("the scenario file was rewritten (%v)", err)
+	}
+}
@@ -0,0 +1,51 @@
+package tui_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/tui"
+)
+
+func key(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// A scenario that includes other files is saved from the editor as a new
+// file, leaving the original and its includes as they were.
+func TestEditorSavesIncludingScenarioAsNewFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	main := strings.Replace(watchScenario, "endpoints = {", `include = "topology.lua",
+	endpoints = {`, 1)
+	topology := `return {
+	endpoints = { { id = 2, kind = "server", address = "127.0.0.1", port = 9003 } },
+}
+`
+	for name, src := range map[string]string{"scenario.lua": main, "topology.lua": topology} {
+		if err := os.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := newDriver(t, tui.New("test", tui.Options{Scenario: "scenario.lua"}))
+	d.waitFor("127.0.0.1:9003", 1)
+	d.send(key("E"))
+	d.waitFor("Saves go to a new file", 1)
+	d.send(key("w"))
+	d.waitFor("Saved as scenario_edited.lua", 1)
+
+	if data, err := os.ReadFile("scenario.lua"); err != nil || string(data) != main {
+		t.Errorf("the original scenario was rewritten (%v)", err)
+	}
+	cfg, err := lua.ReadLuaConfig("scenario_edited.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Endpoints) != 3 {
+		t.Errorf("new file has endpoints %+v, want the included one too", cfg.Endpoints)
+	}
+}
@@ -0,0 +1,57 @@
+package tui_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/samaelod/nabu/tui"
+	"github.com/samaelod/nabu/types"
+)
+
+// Zero is shown for required numbers, optional fields left at zero are blank,
+// and either reads back as the record it came from.
+func TestRecordFormZeroValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		record any
+		want   map[string]string // Field values by Lua key
+	}{
+		{
+			"endpoint",
+			types.Endpoint{ID: 0, Kind: "server", Address: "127.0.0.1", Port: 0},
+			map[string]string{"id": "0", "port": "0", "kind": "server", "bind": "", "timeout": "", "iterations": "", "source_pool": ""},
+		},
+		{
+			"endpoint with options",
+			types.Endpoint{ID: 3, Kind: "client", Port: 80, Bind: true, Timeout: 500, SourcePool: []string{"10.0.0.1", "10.0.0.2"}},
+			map[string]string{"id": "3", "port": "80", "address": "", "bind": "true", "timeout": "500", "source_pool": "10.0.0.1, 10.0.0.2"},
+		},
+		{
+			"message",
+			types.Message{From: 0, To: 0, Kind: "syn"},
+			map[string]string{"from": "0", "to": "0", "t_delta": "0", "value": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tui.NewRecordForm(tt.name, tt.record)
+			got := make(map[string]string)
+			for _, field := range f.Fields {
+				got[field.Key] = field.Input.Value()
+			}
+			for key, want := range tt.want {
+				if v, ok := got[key]; !ok || v != want {
+					t.Errorf("%s = %q, want %q", key, v, want)
+				}
+			}
+
+			back, err := f.Apply()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(back, tt.record) {
+				t.Errorf("applied %+v, want %+v", back, tt.record)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package tui_test
+
+import (
+	"encoding/hex"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/samaelod/nabu/tui"
+	"github.com/samaelod/nabu/types"
+)
+
+// inspect opens an inspector on one message with payload data and runs a
+// search for query.
+func inspect(data []byte, width, height int, query string) tui.Inspector {
+	msgs := []types.Message{{From: 0, To: 1, Kind: "data", Value: hex.EncodeToString(data)}}
+	v := tui.NewInspector(msgs, []int{0}, 0)
+	v.SetSize(width, height)
+	v, _ = v.Update(key("/"))
+	v, _ = v.Update(key(query))
+	v, _ = v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return v
+}
+
+func TestInspectorSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		query   string
+		want    string // Parsed query
+		matches []int
+		err     bool
+	}{
+		{"text", "GET / HTTP/1.1", "HTTP", "HTTP", []int{6}, false},
+		{"hex", "\x00AB\x00AB", "0x4142", "AB", []int{1, 4}, false},
+		{"hex with spaces", "\x00AB", "0x41 42", "AB", []int{1}, false},
+		{"0x in text", "a 0x", "0x", "", nil, false},
+		{"overlapping", "aaaa", "aa", "aa", []int{0, 1, 2}, false},
+		{"no match", "abc", "abd", "abd", nil, false},
+		{"odd hex", "abc", "0x414", "", nil, true},
+		{"invalid hex", "abc", "0xzz", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := inspect([]byte(tt.payload), 120, 30, tt.query)
+			if (v.Err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", v.Err, tt.err)
+			}
+			if tt.err {
+				if !strings.Contains(v.Err.Error(), "invalid hex search") {
+					t.Errorf("err = %v", v.Err)
+				}
+				if !strings.Contains(v.View(), "invalid hex search") {
+					t.Error("error not shown")
+				}
+			}
+			if string(v.Query) != tt.want {
+				t.Errorf("query = %q, want %q", v.Query, tt.want)
+			}
+			if !slices.Equal(v.Matches, tt.matches) {
+				t.Errorf("matches = %v, want %v", v.Matches, tt.matches)
+			}
+		})
+	}
+}
+
+func TestInspectorMatchWraps(t *testing.T) {
+	v := inspect([]byte("x.x.x"), 120, 30, "x")
+	steps := []struct {
+		key  string
+		want int
+	}{
+		{"n", 1}, {"n", 2}, {"n", 0}, {"N", 2}, {"N", 1}, {"N", 0}, {"N", 2},
+	}
+	for i, s := range steps {
+		v, _ = v.Update(key(s.key))
+		if v.Match != s.want {
+			t.Fatalf("step %d (%s): match = %d, want %d", i+1, s.key, v.Match, s.want)
+		}
+		if want := "match " + string(rune('1'+s.want)) + " of 3"; !strings.Contains(v.View(), want) {
+			t.Fatalf("step %d: %q not shown", i+1, want)
+		}
+	}
+
+	// A new search starts again from the first match
+	v, _ = v.Update(key("/"))
+	v, _ = v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if v.Match != 0 || len(v.Matches) != 3 {
+		t.Errorf("after searching again: match %d of %v", v.Match, v.Matches)
+	}
+
+	// n and N do nothing without matches
+	v = inspect([]byte("abc"), 120, 30, "z")
+	for _, k := range []string{"n", "N"} {
+		if v, _ = v.Update(key(k)); v.Match != 0 {
+			t.Errorf("%s without matches: match = %d", k, v.Match)
+		}
+	}
+}
+
+// The hex dump uses fewer bytes per row in a narrow pane and scrolls to the
+// row of the current match.
+func TestInspectorDumpWidth(t *testing.T) {
+	data := make([]byte, 256)
+	copy(data[200:], "needle")
+	tests := []struct {
+		name     string
+		width    int
+		shown    []string // Row offsets in view
+		notShown []string
+	}{
+		{"wide", 130, []string{"000000c0"}, []string{"000000c8"}},
+		{"just wide enough", 117, []string{"000000c0"}, []string{"000000c8"}},
+		{"one column short", 116, []string{"000000c8"}, []string{"000000c0"}},
+		{"half", 90, []string{"000000c8"}, []string{"000000c0"}},
+		{"narrow", 50, []string{"000000c8", "000000cc"}, []string{"000000c4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := inspect(data, tt.width, 12, "needle")
+			if !slices.Equal(v.Matches, []int{200}) {
+				t.Fatalf("matches = %v", v.Matches)
+			}
+			view := v.View()
+			for _, s := range tt.shown {
+				if !strings.Contains(view, s) {
+					t.Errorf("row %s not shown:\n%s", s, view)
+				}
+			}
+			for _, s := range tt.notShown {
+				if strings.Contains(view, s) {
+					t.Errorf("row %s shown:\n%s", s, view)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package tui_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/samaelod/nabu/tui"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// logsDriver runs a scenario whose server 0 logs at info and whose client 1
+// fails to connect, and focuses the logs panel.
+func logsDriver(t *testing.T) *driver {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	scenario := fmt.Sprintf(`return {
+	globals = { timeout = 500 },
+	endpoints = {
+		{ id = 0, kind = "server", address = "127.0.0.1", port = %d },
+		{ id = 1, kind = "client", address = "127.0.0.1" },
+		{ id = 2, kind = "server", address = "127.0.0.1", port = %d },
+	},
+	messages = { { from = 1, to = 2, kind = "syn" } },
+}
+`, freePort(t), freePort(t))
+	if err := os.WriteFile("scenario.lua", []byte(scenario), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newDriver(t, tui.New("test", tui.Options{Scenario: "scenario.lua"}))
+	d.waitFor("[1] 127.0.0.1", 1)
+	d.send(key("r"))
+	d.waitFor("listening on", 1)
+	d.send(key("l"))
+	d.send(key("r"))
+	d.waitFor("connect failed", 1)
+	d.send(tea.KeyMsg{Type: tea.KeyTab})
+	return d
+}
+
+// titled reports whether the logs panel title shows status.
+func titled(view, status string) bool {
+	return regexp.MustCompile(`Logs\s+` + regexp.QuoteMeta(status)).MatchString(view)
+}
+
+// prompt types value into the search or filter prompt opened by k.
+func (d *driver) prompt(k, value string) {
+	d.send(key(k))
+	d.send(tea.KeyMsg{Type: tea.KeyCtrlU})
+	if value != "" {
+		d.send(key(value))
+	}
+	d.send(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestLogFilter(t *testing.T) {
+	d := logsDriver(t)
+
+	tests := []struct {
+		filter string
+		status string   // Shown next to the panel title
+		shown  []string // Lines that pass the filter
+		hidden []string // Lines that do not
+	}{
+		{"0", "endpoint 0", []string{"listening on"}, []string{"connect failed"}},
+		{"1 error", "endpoint 1 • error+", []string{"connect failed"}, []string{"listening on"}},
+		{"0,1", "endpoint 0, 1", []string{"listening on", "connect failed"}, nil},
+		{"WARNING", "warn+", []string{"connect failed"}, []string{"listening on"}},
+		{"2", "endpoint 2", []string{"No log lines match the filter."}, []string{"listening on", "connect failed"}},
+		{"", "", []string{"listening on", "connect failed"}, []string{"No log lines match"}},
+	}
+	for _, tt := range tests {
+		d.prompt("f", tt.filter)
+		view := d.m.View()
+		if tt.status != "" && !titled(view, tt.status) {
+			t.Errorf("filter %q: title does not show %q:\n%s", tt.filter, tt.status, view)
+		}
+		for _, s := range tt.shown {
+			if !strings.Contains(view, s) {
+				t.Errorf("filter %q: %q not shown", tt.filter, s)
+			}
+		}
+		for _, s := range tt.hidden {
+			if strings.Contains(view, s) {
+				t.Errorf("filter %q: %q shown", tt.filter, s)
+			}
+		}
+	}
+
+	// An invalid filter is reported and leaves the current one in place
+	d.prompt("f", "1")
+	d.prompt("f", "1 loud")
+	if view := d.m.View(); !strings.Contains(view, `"loud" is neither an endpoint ID nor a level`) {
+		t.Errorf("invalid filter not reported:\n%s", view)
+	}
+	d.send(tea.KeyMsg{Type: tea.KeyEsc})
+	if view := d.m.View(); !titled(view, "endpoint 1") || strings.Contains(view, "listening on") {
+		t.Errorf("invalid filter replaced the current one:\n%s", view)
+	}
+}
+
+func TestLogSearch(t *testing.T) {
+	d := logsDriver(t)
+	d.prompt("/", "ENDPOINT")
+
+	m := regexp.MustCompile(`/ENDPOINT 1 of (\d+)`).FindStringSubmatch(d.m.View())
+	if m == nil {
+		t.Fatalf("no matches counted:\n%s", d.m.View())
+	}
+	total := m[1]
+	if total == "1" {
+		t.Fatalf("want several matches to step through:\n%s", d.m.View())
+	}
+
+	// n and N step through the matches and wrap around both ends
+	steps := []struct {
+		key  string
+		want string
+	}{
+		{"N", total + " of " + total},
+		{"n", "1 of " + total},
+		{"n", "2 of " + total},
+		{"N", "1 of " + total},
+	}
+	for _, st := range steps {
+		d.send(key(st.key))
+		if view := d.m.View(); !strings.Contains(view, "/ENDPOINT "+st.want) {
+			t.Errorf("after %s: title does not show %q:\n%s", st.key, st.want, view)
+		}
+	}
+
+	d.prompt("/", "no such line")
+	if view := d.m.View(); !strings.Contains(view, "/no such line no matches") {
+		t.Errorf("missing search not reported:\n%s", view)
+	}
+}
@@ -0,0 +1,91 @@
+package tui_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/tui"
+)
+
+// lastRun returns how the last run of a scenario in the recent directory
+// ended, nil if none was recorded.
+func lastRun(t *testing.T) *lua.RunResult {
+	t.Helper()
+	entries, err := lua.ListRecent("recent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.LastRun != nil {
+			return e.LastRun
+		}
+	}
+	return nil
+}
+
+// A run is recorded in the recent index once its last client stopped, not
+// as each client does.
+func TestRecordRunOnceFinished(t *testing.T) {
+	t.Chdir(t.TempDir())
+	scenario := fmt.Sprintf(`return {
+	globals = { timeout = 1000 },
+	endpoints = {
+		{ id = 0, kind = "server", address = "127.0.0.1", port = %d },
+		{ id = 1, kind = "client", address = "127.0.0.1" },
+		{ id = 2, kind = "client", address = "127.0.0.1" },
+	},
+	messages = {
+		{ from = 1, to = 0, kind = "syn" },
+		{ from = 2, to = 0, kind = "syn", t_delta = 2000 },
+	},
+}
+`, freePort(t))
+	if err := os.Mkdir("recent", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("recent/scenario.lua", []byte(scenario), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newDriver(t, tui.New("test", tui.Options{Scenario: "recent/scenario.lua"}))
+	d.waitFor("[2] 127.0.0.1", 1)
+	d.send(key("r"))
+	d.waitFor("listening on", 1)
+	d.send(key("C"))
+
+	// Client 1 is done long before client 2 sends
+	if run := d.until(500*time.Millisecond, func() *lua.RunResult { return lastRun(t) }); run != nil {
+		t.Fatalf("run recorded while client 2 was running: %+v", run)
+	}
+
+	run := d.until(5*time.Second, func() *lua.RunResult { return lastRun(t) })
+	if run == nil {
+		t.Fatal("run never recorded")
+	}
+	if run.Completed != 2 || run.Errors != 0 {
+		t.Errorf("recorded %d completed and %d errors, want 2 and 0", run.Completed, run.Errors)
+	}
+}
+
+// until handles messages until get returns non-nil or timeout passed, and
+// returns the last result of get.
+func (d *driver) until(timeout time.Duration, get func() *lua.RunResult) *lua.RunResult {
+	deadline := time.After(timeout)
+	for {
+		if v := get(); v != nil {
+			return v
+		}
+		select {
+		case msg := <-d.msgs:
+			if msg != nil {
+				d.send(msg)
+			}
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			return get()
+		}
+	}
+}
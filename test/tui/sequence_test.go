@@ -0,0 +1,157 @@
+package tui_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/samaelod/nabu/tui"
+	"github.com/samaelod/nabu/types"
+)
+
+var sequenceConfig = &types.Config{
+	Endpoints: []types.Endpoint{
+		{ID: 2, Kind: "client", Address: "127.0.0.1", Port: 3},
+		{ID: 0, Kind: "server", Address: "127.0.0.1", Port: 1},
+		{ID: 1, Kind: "client", Address: "127.0.0.1", Port: 2},
+	},
+	Messages: []types.Message{
+		{From: 1, To: 0, Kind: "syn"},
+		{From: 0, To: 0, Kind: "data", Value: "4142"},
+		{From: 2, To: 0, Kind: "data"},
+		{From: 0, To: 2, Kind: "fin"},
+		{From: 1, To: 5, Kind: "data"}, // No such endpoint
+		{From: 5, To: 1, Kind: "data"},
+		{From: 2, To: 2, Kind: "data"},
+	},
+}
+
+// sequence opens the view at width with its columns scrolled by col.
+func sequence(width, height, col int) tui.SequenceView {
+	v := tui.NewSequenceView(sequenceConfig)
+	v.SetSize(width, height)
+	for range col {
+		v = v.Update(key("l"))
+	}
+	return v
+}
+
+// row returns the line of message i (0-based) in view.
+func row(t *testing.T, view string, i int) string {
+	t.Helper()
+	gutter := fmt.Sprintf("%5d %+6dms", i+1, 0)
+	for _, line := range strings.Split(view, "\n") {
+		if strings.Contains(line, gutter) {
+			return line
+		}
+	}
+	t.Fatalf("message %d not shown:\n%s", i+1, view)
+	return ""
+}
+
+func TestSequenceArrows(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int // 40 fits one column, 60 two and 120 all three
+		col   int
+		msg   int
+		want  string
+	}{
+		{"left", 120, 0, 0, "│◀──────────── syn ─────────────│"},
+		{"right", 120, 0, 3, "│───────────────────────────── fin ────────────────────────────▶│"},
+		{"self", 120, 0, 1, "│◀ data 2B"},
+		{"self last column", 120, 0, 6, "│◀ data"},
+		{"unknown receiver", 120, 0, 4, "│ data → 5?"},
+		{"unknown sender", 120, 0, 5, "5 → 1 data"},
+		{"sender right of view", 60, 0, 2, "│◀──────── data ──────────"},
+		{"receiver right of view", 60, 0, 3, "│────────── fin ─────────▶"},
+		{"receiver left of view", 60, 1, 0, "◀ syn ──│"},
+		{"sender left of view", 60, 1, 3, "────────── fin ──────────▶│"},
+		{"self out of view", 60, 1, 1, "0 → 0 data 2B"},
+		{"unknown receiver scrolled", 60, 1, 4, "│ data → 5?"},
+		{"narrow", 40, 0, 0, "│◀ syn ─"},
+		{"narrow self", 40, 0, 1, "│◀ data"},
+		{"narrow scrolled", 40, 1, 0, "◀ syn ──│"},
+		{"narrow last column", 40, 2, 3, "─ fin ─▶│"},
+		{"narrow self last column", 40, 2, 6, "│◀ data"},
+		{"both out of view", 40, 2, 0, "1 → 0 syn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := sequence(tt.width, 20, tt.col).View()
+			if line := row(t, view, tt.msg); !strings.Contains(line, tt.want) {
+				t.Errorf("got %q, want it to contain %q", line, tt.want)
+			}
+		})
+	}
+}
+
+// Every message is drawn at any width and column without panicking.
+func TestSequenceSizes(t *testing.T) {
+	for width := range 130 {
+		for col := range 4 {
+			v := sequence(width, 20, col)
+			if col >= 3 && v.Col != 2 {
+				t.Fatalf("scrolled to column %d of 3", v.Col+1)
+			}
+			v.View()
+		}
+	}
+
+	v := sequence(40, 20, 2)
+	if view := v.View(); !strings.Contains(view, "endpoints 3-3 of 3") {
+		t.Errorf("scrolled columns not shown:\n%s", view)
+	}
+	for range 3 {
+		v = v.Update(key("h"))
+	}
+	if v.Col != 0 {
+		t.Errorf("scrolled left to column %d", v.Col)
+	}
+
+	v = tui.NewSequenceView(&types.Config{})
+	v.SetSize(80, 20)
+	if view := v.View(); !strings.Contains(view, "No messages in this scenario.") {
+		t.Errorf("empty scenario:\n%s", view)
+	}
+}
+
+func TestSequenceExecuted(t *testing.T) {
+	v := sequence(120, 9, 0) // Two rows of messages
+	steps := []struct {
+		sender, i int
+		last      int
+	}{
+		{0, 0, 1},
+		{0, 1, 3},
+		{0, 2, 3},  // Past the sender's messages
+		{0, -1, 3}, // Before them
+		{7, 0, 3},  // Sends nothing
+		{0, 0, 1},  // A new pass
+		{2, 1, 6},
+	}
+	for _, s := range steps {
+		v.Executed(s.sender, s.i)
+		if v.Last != s.last {
+			t.Fatalf("Executed(%d, %d): last = %d, want %d", s.sender, s.i, v.Last, s.last)
+		}
+		if v.Cursor != 0 || v.Offset != 0 {
+			t.Fatalf("Executed(%d, %d) moved the cursor to %d without following", s.sender, s.i, v.Cursor)
+		}
+	}
+
+	// Following scrolls each executed message into view
+	v = v.Update(key("f"))
+	if v.Cursor != 6 || v.Offset != 5 {
+		t.Fatalf("follow: cursor %d offset %d, want 6 and 5", v.Cursor, v.Offset)
+	}
+	v.Executed(1, 0)
+	if v.Cursor != 0 || v.Offset != 0 {
+		t.Errorf("cursor %d offset %d after executing the first message", v.Cursor, v.Offset)
+	}
+	v.Executed(0, 1)
+	view := v.View()
+	if v.Cursor != 3 || v.Offset != 2 || !strings.Contains(view, "    4 ") || strings.Contains(view, "    1 ") {
+		t.Errorf("cursor %d offset %d after executing message 4:\n%s", v.Cursor, v.Offset, view)
+	}
+}
@@ -0,0 +1,117 @@
+package tui_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/samaelod/nabu/tui"
+)
+
+const watchScenario = `return {
+	globals = { protocol = "tcp", timeout = 1000, delay = 0 },
+	endpoints = {
+		{ id = 0, kind = "server", address = "127.0.0.1", port = 9001 },
+		{ id = 1, kind = "client", address = "127.0.0.1", port = 9002 },
+	},
+	messages = {
+		{ from = 1, to = 0, kind = "data", value = "01" },
+	},
+}
+`
+
+// driver runs a Model the way bubbletea does: commands run concurrently
+// and their messages are handed to Update one at a time.
+type driver struct {
+	t    *testing.T
+	m    tea.Model
+	msgs chan tea.Msg
+}
+
+func newDriver(t *testing.T, m tea.Model) *driver {
+	d := &driver{t: t, m: m, msgs: make(chan tea.Msg, 64)}
+	d.run(m.Init())
+	d.send(tea.WindowSizeMsg{Width: 140, Height: 40})
+	return d
+}
+
+func (d *driver) run(cmd tea.Cmd) {
+	if cmd != nil {
+		go func() { d.msgs <- cmd() }()
+	}
+}
+
+func (d *driver) send(msg tea.Msg) {
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, cmd := range batch {
+			d.run(cmd)
+		}
+		return
+	}
+	var cmd tea.Cmd
+	d.m, cmd = d.m.Update(msg)
+	d.run(cmd)
+}
+
+// waitFor handles messages until the view contains text count times.
+func (d *driver) waitFor(text string, count int) {
+	d.t.Helper()
+	deadline := time.After(5 * time.Second)
+	for strings.Count(d.m.View(), text) < count {
+		select {
+		case msg := <-d.msgs:
+			if msg != nil {
+				d.send(msg)
+			}
+		case <-deadline:
+			d.t.Fatalf("view never showed %q %d times:\n%s", text, count, d.m.View())
+		}
+	}
+}
+
+// An opened scenario is run from its copy in the recent directory, but the
+// watcher and the u key follow the original and the files it includes.
+func TestWatchReloadsOriginal(t *testing.T) {
+	t.Chdir(t.TempDir())
+	main := strings.Replace(watchScenario, "endpoints = {", `include = "topology.lua",
+	endpoints = {`, 1)
+	topology := `return {
+	endpoints = { { id = 2, kind = "server", address = "127.0.0.1", port = 9003 } },
+}
+`
+	for name, src := range map[string]string{"scenario.lua": main, "topology.lua": topology} {
+		if err := os.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := newDriver(t, tui.New("test", tui.Options{Scenario: "scenario.lua"}))
+	d.waitFor("127.0.0.1:9003", 1)
+
+	edited := strings.Replace(main, "port = 9001", "port = 19001", 1)
+	if err := os.WriteFile("scenario.lua", []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d.waitFor("127.0.0.1:19001", 1)
+
+	if err := os.WriteFile("topology.lua", []byte(strings.Replace(topology, "9003", "19003", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d.waitFor("127.0.0.1:19003", 1)
+
+	// Reloading by hand reads the same files the watcher did
+	reloads := strings.Count(d.m.View(), "Scenario reloaded")
+	d.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	d.waitFor("Scenario reloaded", reloads+1)
+	if view := d.m.View(); !strings.Contains(view, "127.0.0.1:19001") || !strings.Contains(view, "127.0.0.1:19003") {
+		t.Errorf("u undid the reload of the original:\n%s", view)
+	}
+
+	if _, err := os.Stat(filepath.Join("recent", "scenario_1.lua")); err != nil {
+		t.Errorf("no copy saved in the recent directory: %v", err)
+	}
+}
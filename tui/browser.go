@@ -1,16 +1,21 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/samaelod/nabu/pcapreader"
 )
 
 type FileBrowser struct {
@@ -22,6 +27,20 @@ type FileBrowser struct {
 	Width          int
 	Err            error
 	AllowedTypes   []string
+
+	summaries   map[string]string // Capture summaries by path
+	summarizing string            // Capture being read for its summary
+	cancel      context.CancelFunc
+}
+
+// summaryTop is the number of conversations, protocols and endpoints
+// listed in a capture summary.
+const summaryTop = 5
+
+type captureSummaryMsg struct {
+	path    string
+	summary *pcapreader.Summary
+	err     error
 }
 
 type fileItem struct {
@@ -113,6 +132,7 @@ func NewFileBrowser(allowedTypes []string) FileBrowser {
 		List:         l,
 		CurrentDir:   cwd,
 		AllowedTypes: allowedTypes,
+		summaries:    make(map[string]string),
 	}
 	fb.refreshDir()
 	return fb
@@ -235,8 +255,12 @@ func (fb *FileBrowser) updatePreview() {
 		} else {
 			contentStr = string(content)
 		}
-	} else if strings.HasSuffix(nameLower, ".pcap") || strings.HasSuffix(nameLower, ".pcapng") || strings.HasSuffix(nameLower, ".cap") {
-		contentStr = fmt.Sprintf("Capture file\nSize: %d bytes\n\nPreview not available for binary files.", fi.info.Size())
+	} else if isCapture(nameLower) {
+		if summary, ok := fb.summaries[fi.path]; ok {
+			contentStr = summary
+		} else {
+			contentStr = fmt.Sprintf("Capture file\nSize: %d bytes\n\nReading capture...", fi.info.Size())
+		}
 	} else {
 		contentStr = "Preview unavailable for this file type."
 	}
@@ -258,7 +282,49 @@ func (fb *FileBrowser) updatePreview() {
 	fb.PreviewContent = contentStr
 }
 
+func isCapture(name string) bool {
+	return strings.HasSuffix(name, ".pcap") || strings.HasSuffix(name, ".pcapng") || strings.HasSuffix(name, ".cap")
+}
+
+// summarize reads the selected capture for its summary in the background,
+// giving up on the capture read before. Captures are read once.
+func (fb *FileBrowser) summarize() tea.Cmd {
+	path := ""
+	if fi, ok := fb.List.SelectedItem().(fileItem); ok && !fi.isDir && isCapture(strings.ToLower(fi.name)) {
+		path = fi.path
+	}
+	if path == fb.summarizing {
+		return nil
+	}
+	if fb.cancel != nil {
+		fb.cancel()
+	}
+	fb.summarizing, fb.cancel = "", nil
+	if _, ok := fb.summaries[path]; ok || path == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fb.summarizing, fb.cancel = path, cancel
+	return func() tea.Msg {
+		s, err := pcapreader.Summarize(ctx, path, summaryTop)
+		return captureSummaryMsg{path: path, summary: s, err: err}
+	}
+}
+
 func (fb FileBrowser) Update(msg tea.Msg) (FileBrowser, tea.Cmd) {
+	if msg, ok := msg.(captureSummaryMsg); ok {
+		if msg.path == fb.summarizing {
+			fb.summarizing, fb.cancel = "", nil
+		}
+		// Captures left before they were read are read again when selected
+		if !errors.Is(msg.err, context.Canceled) {
+			fb.summaries[msg.path] = renderCaptureSummary(msg.path, msg.summary, msg.err)
+			fb.updatePreview()
+		}
+		return fb, nil
+	}
+
 	var cmd tea.Cmd
 	fb.List, cmd = fb.List.Update(msg)
 
@@ -294,7 +360,53 @@ func (fb FileBrowser) Update(msg tea.Msg) (FileBrowser, tea.Cmd) {
 		}
 	}
 
-	return fb, cmd
+	return fb, tea.Batch(cmd, fb.summarize())
+}
+
+// renderCaptureSummary describes the capture at path for the preview.
+func renderCaptureSummary(path string, s *pcapreader.Summary, err error) string {
+	var sb strings.Builder
+	size := int64(0)
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	if err != nil {
+		fmt.Fprintf(&sb, "Capture file\nSize: %d bytes\n\nCould not read the capture: %v", size, err)
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "Capture file • %s • %s\n", s.Format, formatBytes(float64(size)))
+	fmt.Fprintf(&sb, "Link type:  %s\n", s.LinkType)
+	fmt.Fprintf(&sb, "Packets:    %d (%s captured)\n", s.Packets, formatBytes(float64(s.Bytes)))
+	if s.Packets == 0 {
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Time span:  %s → %s (%s)\n",
+		s.First.Format("2006-01-02 15:04:05"), s.Last.Format("2006-01-02 15:04:05"), s.Span().Round(time.Millisecond))
+	fmt.Fprintf(&sb, "Endpoints:  %d TCP endpoints would be created\n", len(s.Endpoints))
+
+	sb.WriteString("\nProtocols\n")
+	for _, p := range s.Protocols[:min(len(s.Protocols), summaryTop)] {
+		fmt.Fprintf(&sb, "  %-10s %8d  %5.1f%%\n", p.Name, p.Packets, float64(p.Packets)*100/float64(s.Packets))
+	}
+
+	if len(s.Conversations) > 0 {
+		sb.WriteString("\nTop conversations\n")
+		for _, c := range s.Conversations {
+			fmt.Fprintf(&sb, "  %-4s %s ↔ %s  %d packets • %s\n", c.Protocol, c.A, c.B, c.Packets, formatBytes(float64(c.Bytes)))
+		}
+	}
+
+	if len(s.Endpoints) > 0 {
+		sb.WriteString("\nDetected endpoints\n")
+		for _, ep := range s.Endpoints[:min(len(s.Endpoints), summaryTop)] {
+			fmt.Fprintf(&sb, "  %s\n", ep)
+		}
+		if n := len(s.Endpoints) - summaryTop; n > 0 {
+			fmt.Fprintf(&sb, "  ... and %d more\n", n)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
 }
 
 func (fb *FileBrowser) SetSize(width, height int) {
@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/types"
+)
+
+type endpointsRemovedMsg struct {
+	config *types.Config // Scenario without the endpoints
+	ids    []int
+	err    error
+}
+
+// activeEndpointList returns the endpoint list that has the focus.
+func (m *Model) activeEndpointList() *list.Model {
+	if m.activeEndpointPanel == 0 {
+		return &m.serverEndpoints
+	}
+	return &m.clientEndpoints
+}
+
+// targetEndpoints returns the IDs bulk actions apply to: the marked
+// endpoints, or the selected one when none are marked.
+func (m Model) targetEndpoints() []int {
+	if len(m.marked) > 0 {
+		ids := make([]int, 0, len(m.marked))
+		for id := range m.marked {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		return ids
+	}
+	if ep, ok := m.selectedEndpoint(); ok {
+		return []int{ep.ID}
+	}
+	return nil
+}
+
+// toggleMark marks or unmarks the selected endpoint.
+func (m *Model) toggleMark() {
+	ep, ok := m.selectedEndpoint()
+	if !ok {
+		return
+	}
+	if m.marked[ep.ID] {
+		delete(m.marked, ep.ID)
+	} else {
+		m.marked[ep.ID] = true
+	}
+}
+
+// markVisible marks the endpoints the active list shows, which are the
+// ones matching its filter, or unmarks them when all already are.
+func (m *Model) markVisible() {
+	items := m.activeEndpointList().VisibleItems()
+	all := true
+	for _, item := range items {
+		if ep, ok := item.(endpointItem); ok && !m.marked[ep.ID] {
+			all = false
+		}
+	}
+	for _, item := range items {
+		if ep, ok := item.(endpointItem); ok {
+			if all {
+				delete(m.marked, ep.ID)
+			} else {
+				m.marked[ep.ID] = true
+			}
+		}
+	}
+}
+
+// runKind starts every endpoint of kind that is not running yet.
+func (m Model) runKind(kind string) {
+	for _, ep := range m.config.Endpoints {
+		if ep.Kind == kind {
+			m.engine.StartEndpoint(ep.ID)
+		}
+	}
+}
+
+// removeEndpointsCmd drops the endpoints ids, and the messages they send
+// or receive, from the loaded scenario. The scenario file is left alone, so
+// reloading it brings them back.
+func removeEndpointsCmd(cfg *types.Config, ids []int) tea.Cmd {
+	out := *cfg
+	out.Endpoints = slices.DeleteFunc(slices.Clone(cfg.Endpoints), func(ep types.Endpoint) bool {
+		return slices.Contains(ids, ep.ID)
+	})
+	out.Messages = slices.DeleteFunc(slices.Clone(cfg.Messages), func(msg types.Message) bool {
+		return slices.Contains(ids, msg.From) || slices.Contains(ids, msg.To)
+	})
+	out.MessagesByFrom = nil
+	return func() tea.Msg {
+		if err := lua.ValidateConfig(&out); err != nil {
+			return endpointsRemovedMsg{ids: ids, err: err}
+		}
+		return endpointsRemovedMsg{config: &out, ids: ids}
+	}
+}
+
+// describeEndpoints names ids for prompts.
+func describeEndpoints(ids []int) string {
+	if len(ids) == 1 {
+		return fmt.Sprintf("endpoint %d", ids[0])
+	}
+	return fmt.Sprintf("%d endpoints", len(ids))
+}
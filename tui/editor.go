@@ -0,0 +1,479 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/types"
+)
+
+const (
+	sectionEndpoints = iota
+	sectionMessages
+)
+
+// Rows used around the lists: app title, panel borders and titles, status
+// line and footer
+const editorChromeHeight = 7
+
+// ScenarioEditor edits the endpoints and messages of a scenario file and
+// saves it with lua.WriteFile.
+type ScenarioEditor struct {
+	Path    string
+	Config  *types.Config // Scenario being edited, as read from Path
+	SaveAs  string        // Why saves go to a new file rather than Path, empty when they don't
+	Section int           // sectionEndpoints or sectionMessages
+	Cursor  [2]int        // Selected row per section
+	Offset  [2]int        // First row shown per section
+	Form    *RecordForm   // Open while a record is being edited
+	Dirty   bool          // Changed since read or saved
+	Status  string        // Result of the last action
+	Err     error         // Why the last save failed
+
+	editing int    // Row the form edits, -1 when adding
+	confirm string // Key to press again to confirm a delete or discard
+	width   int
+	height  int
+}
+
+type editorOpenedMsg struct {
+	path   string
+	config *types.Config
+	saveAs string
+	err    error
+}
+
+type scenarioSavedMsg struct {
+	path   string
+	saveAs string // Why path is a new file, empty when the scenario was saved in place
+	err    error
+}
+
+type editorClosedMsg struct{}
+
+// openEditorCmd reads path again for editing, without remapping, so the
+// saved file keeps the scenario's own addresses.
+func openEditorCmd(path string, params map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		opts := luaOptions(params)
+		cfg, err := lua.ReadLuaConfigWithOptions(context.Background(), path, opts)
+		if err != nil {
+			return editorOpenedMsg{path: path, err: err}
+		}
+		saveAs, err := saveAsReason(path, cfg, opts)
+		return editorOpenedMsg{path: path, config: cfg, saveAs: saveAs, err: err}
+	}
+}
+
+// saveAsReason says why writing cfg over path would lose what the file
+// does beyond listing the scenario, or returns "" when it would not.
+func saveAsReason(path string, cfg *types.Config, opts lua.Options) (string, error) {
+	if len(cfg.Sources) > 1 {
+		return "it includes, extends or requires other files", nil
+	}
+	params, err := lua.DeclaredParams(path, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(params) > 0 {
+		return "it declares parameters", nil
+	}
+	return "", nil
+}
+
+// saveScenarioCmd writes cfg to path, or to a new file next to it when
+// saveAs gives a reason not to overwrite path.
+func saveScenarioCmd(path, saveAs string, cfg *types.Config) tea.Cmd {
+	return func() tea.Msg {
+		if saveAs != "" {
+			path = editedPath(path)
+		}
+		return scenarioSavedMsg{path: path, saveAs: saveAs, err: lua.WriteFile(path, cfg)}
+	}
+}
+
+// editedPath returns a free name for an edited copy of path, e.g.
+// web_edited.lua, then web_edited_2.lua.
+func editedPath(path string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path)) + "_edited"
+	candidate := base + ".lua"
+	for n := 2; ; n++ {
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d.lua", base, n)
+	}
+}
+
+// NewScenarioEditor opens cfg with the endpoint with ID endpoint selected.
+// A non-empty saveAs says why saves go to a new file rather than path.
+func NewScenarioEditor(path string, cfg *types.Config, saveAs string, endpoint int) ScenarioEditor {
+	ed := ScenarioEditor{Path: path, Config: cfg, SaveAs: saveAs, editing: -1}
+	if saveAs != "" {
+		ed.Status = "Saves go to a new file: the scenario is not rewritten because " + saveAs
+	}
+	for i, ep := range cfg.Endpoints {
+		if ep.ID == endpoint {
+			ed.Cursor[sectionEndpoints] = i
+		}
+	}
+	return ed
+}
+
+// SetSize sets the size of the window the editor is drawn in.
+func (ed *ScenarioEditor) SetSize(width, height int) {
+	ed.width, ed.height = width, height
+	ed.scroll()
+}
+
+func (ed ScenarioEditor) rows() int {
+	return max(ed.height-editorChromeHeight, 1)
+}
+
+func (ed ScenarioEditor) count(section int) int {
+	if ed.Config == nil {
+		return 0
+	}
+	if section == sectionEndpoints {
+		return len(ed.Config.Endpoints)
+	}
+	return len(ed.Config.Messages)
+}
+
+// scroll keeps the selected rows in view.
+func (ed *ScenarioEditor) scroll() {
+	for s := range ed.Cursor {
+		ed.Cursor[s] = max(min(ed.Cursor[s], ed.count(s)-1), 0)
+		if ed.Cursor[s] < ed.Offset[s] {
+			ed.Offset[s] = ed.Cursor[s]
+		}
+		if ed.Cursor[s] >= ed.Offset[s]+ed.rows() {
+			ed.Offset[s] = ed.Cursor[s] - ed.rows() + 1
+		}
+	}
+}
+
+func (ed *ScenarioEditor) changed(status string) {
+	ed.Dirty = true
+	ed.Status, ed.Err = status, nil
+	ed.scroll()
+}
+
+// nextID returns an endpoint ID not used yet.
+func (ed ScenarioEditor) nextID() int {
+	id := 0
+	for _, ep := range ed.Config.Endpoints {
+		id = max(id, ep.ID+1)
+	}
+	return id
+}
+
+// Update edits the scenario. Leaving is reported with editorClosedMsg.
+func (ed ScenarioEditor) Update(msg tea.Msg) (ScenarioEditor, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return ed, nil
+	}
+
+	if ed.Form != nil {
+		switch key.String() {
+		case "enter":
+			ed.submit()
+			return ed, nil
+		case "esc":
+			ed.Form = nil
+			return ed, nil
+		}
+		form, cmd := ed.Form.Update(msg)
+		ed.Form = &form
+		return ed, cmd
+	}
+
+	// A delete or discard waits for the same key again
+	confirm := ed.confirm
+	ed.confirm = ""
+
+	s := ed.Section
+	switch key.String() {
+	case "tab", "shift+tab":
+		ed.Section = 1 - ed.Section
+	case "up", "k":
+		ed.Cursor[s]--
+		ed.scroll()
+	case "down", "j":
+		ed.Cursor[s]++
+		ed.scroll()
+	case "shift+up", "K":
+		ed.move(-1)
+	case "shift+down", "J":
+		ed.move(1)
+	case "a":
+		ed.add()
+	case "enter", "e":
+		ed.edit()
+	case "c":
+		ed.duplicate()
+	case "d":
+		if ed.count(s) == 0 {
+			break
+		}
+		if confirm == "d" {
+			ed.delete()
+			break
+		}
+		ed.confirm = "d"
+		ed.Status = "Press d again to delete " + ed.describe(s, ed.Cursor[s])
+	case "ctrl+s", "w":
+		// The problems are shown in the status line
+		if lua.ValidateConfig(ed.Config) != nil {
+			break
+		}
+		ed.Status, ed.Err = "Saving...", nil
+		return ed, saveScenarioCmd(ed.Path, ed.SaveAs, ed.Config)
+	case "esc":
+		if ed.Dirty && confirm != "esc" {
+			ed.confirm = "esc"
+			ed.Status = "Unsaved changes: press esc again to discard them, w to save"
+			break
+		}
+		return ed, func() tea.Msg { return editorClosedMsg{} }
+	}
+	return ed, nil
+}
+
+// describe names row i of section for status messages.
+func (ed ScenarioEditor) describe(section, i int) string {
+	if section == sectionEndpoints {
+		ep := ed.Config.Endpoints[i]
+		used := 0
+		for _, msg := range ed.Config.Messages {
+			if msg.From == ep.ID || msg.To == ep.ID {
+				used++
+			}
+		}
+		return fmt.Sprintf("endpoint %d (used by %d messages)", ep.ID, used)
+	}
+	return fmt.Sprintf("message %d", i+1)
+}
+
+func (ed *ScenarioEditor) add() {
+	ed.editing = -1
+	if ed.Section == sectionEndpoints {
+		form := NewRecordForm("New endpoint", types.Endpoint{ID: ed.nextID(), Kind: "client", Address: "127.0.0.1"})
+		ed.Form = &form
+		return
+	}
+
+	// Start from the selected message, which usually shares its peers
+	msg := types.Message{Kind: "data"}
+	if len(ed.Config.Messages) > 0 {
+		cur := ed.Config.Messages[ed.Cursor[sectionMessages]]
+		msg.From, msg.To = cur.From, cur.To
+	}
+	form := NewRecordForm("New message", msg)
+	ed.Form = &form
+}
+
+func (ed *ScenarioEditor) edit() {
+	i := ed.Cursor[ed.Section]
+	if ed.count(ed.Section) == 0 {
+		return
+	}
+	ed.editing = i
+	var form RecordForm
+	if ed.Section == sectionEndpoints {
+		form = NewRecordForm(fmt.Sprintf("Endpoint %d", ed.Config.Endpoints[i].ID), ed.Config.Endpoints[i])
+	} else {
+		form = NewRecordForm(fmt.Sprintf("Message %d", i+1), ed.Config.Messages[i])
+	}
+	ed.Form = &form
+}
+
+// submit applies the open form to the scenario.
+func (ed *ScenarioEditor) submit() {
+	v, err := ed.Form.Apply()
+	if err != nil {
+		ed.Form.Err = err
+		return
+	}
+	ed.Form = nil
+
+	at := ed.Cursor[ed.Section] + 1 // Added records go below the selection
+	if ed.count(ed.Section) == 0 {
+		at = 0
+	}
+
+	switch rec := v.(type) {
+	case types.Endpoint:
+		if ed.editing < 0 {
+			ed.Config.Endpoints = slices.Insert(ed.Config.Endpoints, at, rec)
+			ed.Cursor[sectionEndpoints] = at
+			ed.changed(fmt.Sprintf("Added endpoint %d", rec.ID))
+			return
+		}
+		old := ed.Config.Endpoints[ed.editing]
+		ed.Config.Endpoints[ed.editing] = rec
+		status := fmt.Sprintf("Updated endpoint %d", rec.ID)
+		if old.ID != rec.ID {
+			// Keep the endpoint's messages attached to it
+			n := 0
+			for i := range ed.Config.Messages {
+				msg := &ed.Config.Messages[i]
+				if msg.From == old.ID {
+					msg.From = rec.ID
+					n++
+				}
+				if msg.To == old.ID {
+					msg.To = rec.ID
+					n++
+				}
+			}
+			status = fmt.Sprintf("Renumbered endpoint %d to %d in %d message fields", old.ID, rec.ID, n)
+		}
+		ed.changed(status)
+
+	case types.Message:
+		if ed.editing < 0 {
+			ed.Config.Messages = slices.Insert(ed.Config.Messages, at, rec)
+			ed.Cursor[sectionMessages] = at
+			ed.changed(fmt.Sprintf("Added message %d", at+1))
+			return
+		}
+		ed.Config.Messages[ed.editing] = rec
+		ed.changed(fmt.Sprintf("Updated message %d", ed.editing+1))
+	}
+}
+
+func (ed *ScenarioEditor) duplicate() {
+	s := ed.Section
+	i := ed.Cursor[s]
+	if ed.count(s) == 0 {
+		return
+	}
+	if s == sectionEndpoints {
+		ep := ed.Config.Endpoints[i]
+		ep.ID = ed.nextID()
+		ep.SourcePool = slices.Clone(ep.SourcePool)
+		ed.Config.Endpoints = slices.Insert(ed.Config.Endpoints, i+1, ep)
+		ed.Cursor[s] = i + 1
+		ed.changed(fmt.Sprintf("Duplicated endpoint %d as %d", ed.Config.Endpoints[i].ID, ep.ID))
+		return
+	}
+	ed.Config.Messages = slices.Insert(ed.Config.Messages, i+1, ed.Config.Messages[i])
+	ed.Cursor[s] = i + 1
+	ed.changed(fmt.Sprintf("Duplicated message %d", i+1))
+}
+
+func (ed *ScenarioEditor) delete() {
+	s := ed.Section
+	i := ed.Cursor[s]
+	status := "Deleted " + ed.describe(s, i)
+	if s == sectionEndpoints {
+		ed.Config.Endpoints = slices.Delete(ed.Config.Endpoints, i, i+1)
+	} else {
+		ed.Config.Messages = slices.Delete(ed.Config.Messages, i, i+1)
+	}
+	ed.changed(status)
+}
+
+// move swaps the selected row with its neighbour in direction dir.
+func (ed *ScenarioEditor) move(dir int) {
+	s := ed.Section
+	i, j := ed.Cursor[s], ed.Cursor[s]+dir
+	if j < 0 || j >= ed.count(s) {
+		return
+	}
+	if s == sectionEndpoints {
+		eps := ed.Config.Endpoints
+		eps[i], eps[j] = eps[j], eps[i]
+	} else {
+		msgs := ed.Config.Messages
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	ed.Cursor[s] = j
+	ed.changed(fmt.Sprintf("Moved %s", ed.describe(s, j)))
+}
+
+// View renders the endpoint and message lists, or the open form.
+func (ed ScenarioEditor) View() string {
+	if ed.Form != nil {
+		return lipgloss.Place(ed.width, ed.rows()+5, lipgloss.Center, lipgloss.Center, ed.Form.View(ed.width-8))
+	}
+
+	epWidth := max(ed.width*2/5, 30)
+	msgWidth := ed.width - epWidth
+
+	var eps []string
+	for _, ep := range ed.Config.Endpoints {
+		eps = append(eps, fmt.Sprintf("[%d] %-6s %s:%d", ep.ID, ep.Kind, ep.Address, ep.Port))
+	}
+	var msgs []string
+	for i, msg := range ed.Config.Messages {
+		line := fmt.Sprintf("%4d %-5s %d → %d  +%dms", i+1, msg.Kind, msg.From, msg.To, msg.TDelta)
+		if n := len(msg.Value) / 2; n > 0 {
+			line += fmt.Sprintf("  %dB", n)
+		}
+		msgs = append(msgs, line)
+	}
+
+	title := "Edit • " + ed.Path
+	if ed.Dirty {
+		title += " *"
+	}
+	lists := lipgloss.JoinHorizontal(lipgloss.Top,
+		ed.renderList("Endpoints", sectionEndpoints, eps, epWidth),
+		ed.renderList("Messages", sectionMessages, msgs, msgWidth),
+	)
+
+	// Status, or the problems that would stop the scenario from loading
+	errStyle := lipgloss.NewStyle().Foreground(colorError)
+	var status string
+	switch invalid := lua.ValidateConfig(ed.Config); {
+	case ed.confirm != "":
+		status = styleSelected.Render(ed.Status)
+	case ed.Err != nil:
+		status = errStyle.Render(truncate(ed.Err.Error(), ed.width-2))
+	case invalid != nil:
+		status = errStyle.Render(truncate(invalid.Error(), ed.width-2))
+	default:
+		status = styleSubtext.Render(ed.Status)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		" "+styleSubtext.Render(truncate(title, ed.width-2)),
+		lists,
+		" "+status,
+	)
+}
+
+func (ed ScenarioEditor) renderList(title string, section int, rows []string, width int) string {
+	var lines []string
+	end := min(ed.Offset[section]+ed.rows(), len(rows))
+	for i := ed.Offset[section]; i < end; i++ {
+		line := truncate(rows[i], width-4)
+		if i == ed.Cursor[section] && section == ed.Section {
+			line = styleSelected.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(rows) == 0 {
+		lines = append(lines, styleSubtext.Render("None yet, press a to add one."))
+	}
+
+	style := stylePanelTitled
+	if section == ed.Section {
+		style = style.BorderForeground(colorSecondary)
+	}
+	return style.
+		Width(width - 2).
+		Height(ed.rows() + 1).
+		Render(styleTitle.Render(title) + " " + styleSubtext.Render(fmt.Sprint(len(rows))) + "\n" + strings.Join(lines, "\n"))
+}
@@ -0,0 +1,290 @@
+package tui
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// payloadKey is the Lua key of the hex payload of a message, which the form
+// can show as text.
+const payloadKey = "value"
+
+type formField struct {
+	Key   string // Lua key
+	index int
+	Input textinput.Model
+	Text  bool // Payload shown as escaped text rather than hex
+}
+
+// RecordForm edits the fields of a struct tagged with Lua keys, such as an
+// endpoint or a message. Numbers, booleans, strings and string lists are
+// edited as text; lists are comma separated.
+type RecordForm struct {
+	Title  string
+	Fields []formField
+	Cursor int
+	Err    error // Why the last submit was rejected
+
+	typ reflect.Type
+}
+
+// NewRecordForm fills a form from record, which must be a struct.
+func NewRecordForm(title string, record any) RecordForm {
+	v := reflect.ValueOf(record)
+	f := RecordForm{Title: title, typ: v.Type()}
+	for i := 0; i < v.NumField(); i++ {
+		key, opts, _ := strings.Cut(f.typ.Field(i).Tag.Get("lua"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		in := textinput.New()
+		in.Prompt = ""
+		in.CharLimit = 0
+		in.Width = 48
+
+		field := formField{Key: key, index: i, Input: in}
+		field.Input.SetValue(formatField(v.Field(i), opts == "omitempty"))
+		if key == payloadKey {
+			field.Input.Placeholder = "hex, ctrl+t for text"
+			// Start with text for printable payloads
+			if b, err := hex.DecodeString(field.Input.Value()); err == nil && len(b) > 0 && isText(b) {
+				field.Text = true
+				field.Input.SetValue(escapeText(b))
+			}
+		}
+		f.Fields = append(f.Fields, field)
+	}
+	f.focus(0)
+	return f
+}
+
+// formatField renders v for editing. Zero values of optional fields, those
+// tagged omitempty, are left blank.
+func formatField(v reflect.Value, optional bool) string {
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ", ")
+	}
+	if optional && v.IsZero() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func (f *RecordForm) focus(i int) {
+	if len(f.Fields) == 0 {
+		return
+	}
+	f.Fields[f.Cursor].Input.Blur()
+	f.Cursor = (i + len(f.Fields)) % len(f.Fields)
+	f.Fields[f.Cursor].Input.Focus()
+}
+
+// Apply parses the fields into a new value of the record's type.
+func (f RecordForm) Apply() (any, error) {
+	v := reflect.New(f.typ).Elem()
+	for _, field := range f.Fields {
+		s := strings.TrimSpace(field.Input.Value())
+		if field.Key == payloadKey {
+			if field.Text {
+				s = field.Input.Value() // Spaces are part of the text
+			} else {
+				s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+			}
+		}
+		if err := parseField(v.Field(field.index), s, field.Text); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Key, err)
+		}
+	}
+	return v.Interface(), nil
+}
+
+func parseField(v reflect.Value, s string, text bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		if text {
+			b, err := unescapeText(s)
+			if err != nil {
+				return err
+			}
+			s = hex.EncodeToString(b)
+		}
+		v.SetString(s)
+	case reflect.Int:
+		if s == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		if s == "" {
+			return nil
+		}
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		if s == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("cannot edit %s fields", v.Kind())
+	}
+	return nil
+}
+
+// togglePayload switches the focused payload field between hex and text.
+func (f *RecordForm) togglePayload() {
+	field := &f.Fields[f.Cursor]
+	if field.Key != payloadKey {
+		return
+	}
+	if field.Text {
+		b, err := unescapeText(field.Input.Value())
+		if err != nil {
+			f.Err = err
+			return
+		}
+		field.Input.SetValue(hex.EncodeToString(b))
+	} else {
+		b, err := hex.DecodeString(strings.ReplaceAll(field.Input.Value(), " ", ""))
+		if err != nil {
+			f.Err = fmt.Errorf("%s: invalid hex", field.Key)
+			return
+		}
+		field.Input.SetValue(escapeText(b))
+	}
+	field.Text = !field.Text
+	field.Input.CursorEnd()
+	f.Err = nil
+}
+
+// isText reports whether b reads as text, allowing line breaks and tabs.
+func isText(b []byte) bool {
+	for _, c := range b {
+		if (c < 0x20 || c > 0x7e) && c != '\r' && c != '\n' && c != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeText writes b with Go escapes for non-printable bytes, e.g. "\r\n".
+func escapeText(b []byte) string {
+	q := strconv.Quote(string(b))
+	return strings.ReplaceAll(q[1:len(q)-1], `\"`, `"`)
+}
+
+// unescapeText reverses escapeText; a bare double quote stands for itself.
+func unescapeText(s string) ([]byte, error) {
+	var out []byte
+	for len(s) > 0 {
+		if s[0] == '"' {
+			out = append(out, '"')
+			s = s[1:]
+			continue
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			return nil, fmt.Errorf("invalid escape in %q", s)
+		}
+		if multibyte {
+			out = append(out, string(r)...)
+		} else {
+			out = append(out, byte(r))
+		}
+		s = tail
+	}
+	return out, nil
+}
+
+// Update moves between fields and edits the focused one. Enter and esc are
+// handled by the caller.
+func (f RecordForm) Update(msg tea.Msg) (RecordForm, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "tab", "down":
+			f.focus(f.Cursor + 1)
+			return f, nil
+		case "shift+tab", "up":
+			f.focus(f.Cursor - 1)
+			return f, nil
+		case "ctrl+t":
+			f.togglePayload()
+			return f, nil
+		}
+	}
+
+	if len(f.Fields) == 0 {
+		return f, nil
+	}
+	var cmd tea.Cmd
+	f.Fields[f.Cursor].Input, cmd = f.Fields[f.Cursor].Input.Update(msg)
+	return f, cmd
+}
+
+func (f RecordForm) View(width int) string {
+	title := styleTitle.MarginBottom(1).Render(f.Title)
+
+	labelWidth := 0
+	for _, field := range f.Fields {
+		labelWidth = max(labelWidth, len(field.Key)+7)
+	}
+	label := lipgloss.NewStyle().Width(labelWidth + 2)
+
+	var lines []string
+	for i, field := range f.Fields {
+		name := field.Key
+		if field.Key == payloadKey {
+			if field.Text {
+				name += " (text)"
+			} else {
+				name += " (hex)"
+			}
+		}
+		if i == f.Cursor {
+			name = styleSelected.Render(name)
+		} else {
+			name = styleValue.Render(name)
+		}
+		lines = append(lines, label.Render(name)+field.Input.View())
+	}
+
+	body := title + "\n" + strings.Join(lines, "\n")
+	if f.Err != nil {
+		body += "\n\n" + lipgloss.NewStyle().Foreground(colorError).Render(f.Err.Error())
+	}
+
+	return stylePanelTitled.
+		BorderForeground(colorSecondary).
+		Width(min(width, 100)).
+		Render(body)
+}
@@ -0,0 +1,353 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/samaelod/nabu/types"
+)
+
+const (
+	// Rows used around the panels: app title, panel borders and titles,
+	// search line and footer
+	inspectorChromeHeight = 6
+	inspectorListWidth    = 34
+)
+
+var (
+	styleMatch        = lipgloss.NewStyle().Background(colorSubtext).Foreground(colorText)
+	styleCurrentMatch = lipgloss.NewStyle().Background(colorSecondary).Foreground(lipgloss.Color("#000000"))
+)
+
+// Inspector lists messages and shows the payload of the selected one as a
+// hex dump with an ASCII column and as text, with search.
+type Inspector struct {
+	Messages  []types.Message // All messages of the scenario
+	Indices   []int           // Listed messages, as indices into Messages
+	Cursor    int             // Selected entry of Indices
+	Offset    int             // First entry of Indices shown
+	Searching bool            // Typing a query
+	Query     []byte
+	Matches   []int // Offsets of Query in the payload
+	Match     int   // Current entry of Matches
+	Err       error // Invalid search query
+
+	input  textinput.Model
+	pane   viewport.Model
+	width  int
+	height int
+}
+
+func NewInspector(messages []types.Message, indices []int, cursor int) Inspector {
+	in := textinput.New()
+	in.Prompt = "/"
+	in.Placeholder = "text, or 0x followed by hex bytes"
+	in.CharLimit = 256
+
+	v := Inspector{Messages: messages, Indices: indices, input: in, pane: viewport.New(0, 0)}
+	v.selectEntry(cursor)
+	return v
+}
+
+// SetSize sets the size of the window the inspector is drawn in.
+func (v *Inspector) SetSize(width, height int) {
+	v.width, v.height = width, height
+	v.pane.Width = max(width-inspectorListWidth-6, 10)
+	v.pane.Height = v.rows()
+	v.input.Width = max(width-8, 10)
+	v.scrollList()
+	v.refresh()
+}
+
+func (v Inspector) rows() int {
+	return max(v.height-inspectorChromeHeight, 1)
+}
+
+// selectEntry selects entry i of Indices and searches its payload again.
+func (v *Inspector) selectEntry(i int) {
+	v.Cursor = max(min(i, len(v.Indices)-1), 0)
+	v.scrollList()
+	v.pane.GotoTop()
+	v.search()
+}
+
+// scrollList keeps the selected entry in view.
+func (v *Inspector) scrollList() {
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+v.rows() {
+		v.Offset = v.Cursor - v.rows() + 1
+	}
+	// Use the room left below the last entry after growing
+	v.Offset = max(min(v.Offset, len(v.Indices)-v.rows()), 0)
+}
+
+func (v Inspector) message() (types.Message, bool) {
+	if len(v.Indices) == 0 {
+		return types.Message{}, false
+	}
+	return v.Messages[v.Indices[v.Cursor]], true
+}
+
+func (v Inspector) payload() ([]byte, error) {
+	msg, ok := v.message()
+	if !ok {
+		return nil, nil
+	}
+	return hex.DecodeString(msg.Value)
+}
+
+// parseQuery reads a search as text, or as hex bytes after "0x".
+func parseQuery(s string) ([]byte, error) {
+	if h, ok := strings.CutPrefix(s, "0x"); ok {
+		b, err := hex.DecodeString(strings.ReplaceAll(h, " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex search %q", s)
+		}
+		return b, nil
+	}
+	return []byte(s), nil
+}
+
+// search finds Query in the selected payload and scrolls to the first match.
+func (v *Inspector) search() {
+	v.Matches, v.Match = nil, 0
+	data, _ := v.payload()
+	if len(v.Query) > 0 {
+		for off := 0; off < len(data); {
+			i := bytes.Index(data[off:], v.Query)
+			if i < 0 {
+				break
+			}
+			v.Matches = append(v.Matches, off+i)
+			off += i + 1
+		}
+	}
+	v.refresh()
+	v.showMatch()
+}
+
+// showMatch scrolls the hex dump to the current match.
+func (v *Inspector) showMatch() {
+	if len(v.Matches) == 0 {
+		return
+	}
+	row := v.Matches[v.Match]/v.dumpWidth() + 2 // Below the summary lines
+	if row < v.pane.YOffset || row >= v.pane.YOffset+v.pane.Height {
+		v.pane.SetYOffset(row)
+	}
+}
+
+// Update moves between messages, scrolls the payload and edits the search.
+// Esc outside of a search is handled by the caller.
+func (v Inspector) Update(msg tea.Msg) (Inspector, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	if v.Searching {
+		switch key.String() {
+		case "enter":
+			v.Searching = false
+			v.input.Blur()
+			v.Query, v.Err = parseQuery(v.input.Value())
+			v.search()
+			return v, nil
+		case "esc":
+			v.Searching = false
+			v.input.Blur()
+			return v, nil
+		}
+		var cmd tea.Cmd
+		v.input, cmd = v.input.Update(msg)
+		return v, cmd
+	}
+
+	switch key.String() {
+	case "up", "k":
+		v.selectEntry(v.Cursor - 1)
+	case "down", "j":
+		v.selectEntry(v.Cursor + 1)
+	case "pgup", "ctrl+u":
+		v.pane.HalfViewUp()
+	case "pgdown", "ctrl+d":
+		v.pane.HalfViewDown()
+	case "g", "home":
+		v.pane.GotoTop()
+	case "G", "end":
+		v.pane.GotoBottom()
+	case "/":
+		v.Searching = true
+		return v, v.input.Focus()
+	case "n", "N":
+		if len(v.Matches) > 0 {
+			step := 1
+			if key.String() == "N" {
+				step = len(v.Matches) - 1
+			}
+			v.Match = (v.Match + step) % len(v.Matches)
+			v.refresh()
+			v.showMatch()
+		}
+	}
+	return v, nil
+}
+
+// dumpWidth returns how many bytes fit on one hex dump row: 16, or fewer
+// in a narrow pane.
+func (v Inspector) dumpWidth() int {
+	n := 16
+	// Offset and separators take 13 columns, each byte 4
+	for n > 4 && 13+4*n > v.pane.Width {
+		n /= 2
+	}
+	return n
+}
+
+// refresh renders the selected payload into the scrollable pane.
+func (v *Inspector) refresh() {
+	msg, ok := v.message()
+	if !ok {
+		v.pane.SetContent(styleSubtext.Render("No messages."))
+		return
+	}
+	data, err := v.payload()
+	if err != nil {
+		v.pane.SetContent(lipgloss.NewStyle().Foreground(colorError).Render("Invalid hex payload: " + err.Error()))
+		return
+	}
+
+	summary := fmt.Sprintf("%s %d → %d • %d bytes • +%d ms", msg.Kind, msg.From, msg.To, len(data), msg.TDelta)
+	lines := []string{styleValue.Render(truncate(summary, v.pane.Width)), ""}
+	if len(data) == 0 {
+		lines = append(lines, styleSubtext.Render("No payload."))
+		v.pane.SetContent(strings.Join(lines, "\n"))
+		return
+	}
+
+	// Style of each byte by the match covering it
+	marks := make([]int, len(data)) // 0 none, 1 match, 2 current match
+	for i, off := range v.Matches {
+		for j := off; j < off+len(v.Query) && j < len(data); j++ {
+			if i == v.Match {
+				marks[j] = 2
+			} else if marks[j] == 0 {
+				marks[j] = 1
+			}
+		}
+	}
+	mark := func(i int, s string) string {
+		switch marks[i] {
+		case 1:
+			return styleMatch.Render(s)
+		case 2:
+			return styleCurrentMatch.Render(s)
+		}
+		return s
+	}
+
+	width := v.dumpWidth()
+	for row := 0; row < len(data); row += width {
+		var hexCol, asciiCol strings.Builder
+		for i := row; i < row+width; i++ {
+			if i == row+width/2 {
+				hexCol.WriteString(" ")
+			}
+			if i >= len(data) {
+				hexCol.WriteString("   ")
+				continue
+			}
+			hexCol.WriteString(mark(i, fmt.Sprintf("%02x", data[i])) + " ")
+			asciiCol.WriteString(mark(i, string(printable(data[i]))))
+		}
+		lines = append(lines, styleSubtext.Render(fmt.Sprintf("%08x  ", row))+hexCol.String()+" |"+asciiCol.String()+"|")
+	}
+
+	lines = append(lines, "", styleSelected.Render("Text"))
+	if !utf8.Valid(data) {
+		lines = append(lines, styleSubtext.Render("Not valid UTF-8, non-printable bytes shown as '.'"))
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.Map(func(r rune) rune {
+			switch {
+			case r == '\r':
+				return -1
+			case r == '\t':
+				return ' '
+			case r < 0x20 || r == 0x7f || r == utf8.RuneError:
+				return '.'
+			}
+			return r
+		}, line)
+		// Wrap to the pane, which does not wrap by itself
+		r := []rune(line)
+		for v.pane.Width > 0 && len(r) > v.pane.Width {
+			lines = append(lines, string(r[:v.pane.Width]))
+			r = r[v.pane.Width:]
+		}
+		lines = append(lines, string(r))
+	}
+
+	v.pane.SetContent(strings.Join(lines, "\n"))
+}
+
+func printable(b byte) byte {
+	if b < 0x20 || b > 0x7e {
+		return '.'
+	}
+	return b
+}
+
+// View renders the message list next to the payload pane.
+func (v Inspector) View() string {
+	var items []string
+	end := min(v.Offset+v.rows(), len(v.Indices))
+	for i := v.Offset; i < end; i++ {
+		msg := v.Messages[v.Indices[i]]
+		line := truncate(fmt.Sprintf("%4d %-5s %d → %d %dB", v.Indices[i]+1, msg.Kind, msg.From, msg.To, len(msg.Value)/2),
+			inspectorListWidth-4)
+		if i == v.Cursor {
+			line = styleSelected.Render(line)
+		}
+		items = append(items, line)
+	}
+
+	position := fmt.Sprintf("%d/%d", min(v.Cursor+1, len(v.Indices)), len(v.Indices))
+	list := stylePanelTitled.
+		Width(inspectorListWidth).
+		Height(v.rows() + 1).
+		Render(styleTitle.Render("Messages") + " " + styleSubtext.Render(position) + "\n" + strings.Join(items, "\n"))
+
+	pane := stylePanelTitled.
+		BorderForeground(colorSecondary).
+		Width(v.width - inspectorListWidth - 4).
+		Height(v.rows() + 1).
+		Render(styleTitle.Render("Payload") + "\n" + v.pane.View())
+
+	var search string
+	switch {
+	case v.Searching:
+		search = v.input.View()
+	case v.Err != nil:
+		search = lipgloss.NewStyle().Foreground(colorError).Render(v.Err.Error())
+	case len(v.Query) > 0 && len(v.Matches) > 0:
+		search = styleSubtext.Render(fmt.Sprintf("/%s • match %d of %d", v.input.Value(), v.Match+1, len(v.Matches)))
+	case len(v.Query) > 0:
+		search = styleSubtext.Render(fmt.Sprintf("/%s • no matches", v.input.Value()))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, list, pane),
+		" "+search,
+	)
+}
@@ -0,0 +1,247 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/samaelod/nabu/engine"
+)
+
+// logsInterval bounds how often lines logged during a run are redrawn, so a
+// busy run does not filter and render the whole buffer for every line.
+const logsInterval = 100 * time.Millisecond
+
+// logsTickMsg redraws the logs panel with the lines logged since the last
+// redraw; gen ties it to the engine that logged them.
+type logsTickMsg struct {
+	gen int
+}
+
+// scheduleLogs asks for a redraw of the logs panel, unless one is already
+// pending.
+func (m *Model) scheduleLogs() tea.Cmd {
+	if m.logsPending {
+		return nil
+	}
+	m.logsPending = true
+	gen := m.statsGen
+	return tea.Tick(logsInterval, func(time.Time) tea.Msg { return logsTickMsg{gen: gen} })
+}
+
+// Prompts typed into the logs panel
+const (
+	logPromptNone = iota
+	logPromptSearch
+	logPromptFilter
+)
+
+// logFilter selects the log entries shown in the logs panel.
+type logFilter struct {
+	Endpoints []int        // Only entries about these endpoints, none for all
+	Level     engine.Level // Only entries at or above this level, 0 for all
+}
+
+// parseLogFilter reads endpoint IDs and a level name separated by spaces or
+// commas, such as "1 3 warn". An empty string clears the filter.
+func parseLogFilter(s string) (logFilter, error) {
+	var f logFilter
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if id, err := strconv.Atoi(field); err == nil {
+			f.Endpoints = append(f.Endpoints, id)
+			continue
+		}
+		switch strings.ToLower(field) {
+		case "debug", "info", "warn", "warning", "error":
+			f.Level = engine.ParseLevel(field)
+		default:
+			return logFilter{}, fmt.Errorf("%q is neither an endpoint ID nor a level", field)
+		}
+	}
+	return f, nil
+}
+
+func (f logFilter) String() string {
+	var parts []string
+	if len(f.Endpoints) > 0 {
+		ids := make([]string, len(f.Endpoints))
+		for i, id := range f.Endpoints {
+			ids[i] = strconv.Itoa(id)
+		}
+		parts = append(parts, "endpoint "+strings.Join(ids, ", "))
+	}
+	if f.Level > 0 {
+		parts = append(parts, f.Level.String()+"+")
+	}
+	return strings.Join(parts, " • ")
+}
+
+func (f logFilter) match(e engine.LogEntry) bool {
+	if e.Level < f.Level {
+		return false
+	}
+	return len(f.Endpoints) == 0 || slices.Contains(f.Endpoints, e.Endpoint)
+}
+
+// newLogInput returns the input used for log searches and filters.
+func newLogInput() textinput.Model {
+	in := textinput.New()
+	in.CharLimit = 128
+	return in
+}
+
+// logsFilter returns the filter in effect, which follows the selected
+// endpoint when only its logs are shown.
+func (m Model) logsFilter() logFilter {
+	f := m.logFilter
+	if m.logSelectedOnly {
+		f.Endpoints = nil
+		if ep, ok := m.selectedEndpoint(); ok {
+			f.Endpoints = []int{ep.ID}
+		}
+	}
+	return f
+}
+
+// refreshLogs renders the log entries that pass the filter into the logs
+// viewport, highlighting matches of the search. The viewport keeps following
+// new lines while it is scrolled to the bottom.
+func (m *Model) refreshLogs() {
+	if m.engine == nil || m.engine.Log == nil {
+		return
+	}
+	entries := m.engine.Log.Entries()
+	if len(entries) == 0 {
+		return
+	}
+	m.logContent = m.engine.Log.ReadAll()
+
+	filter := m.logsFilter()
+	var lines []string
+	for _, e := range entries {
+		if filter.match(e) {
+			lines = append(lines, strings.Split(e.String(), "\n")...)
+		}
+	}
+
+	var re *regexp.Regexp
+	if m.logQuery != "" {
+		re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(m.logQuery))
+	}
+	m.logMatches = m.logMatches[:0]
+	for i, line := range lines {
+		if re != nil && re.MatchString(line) {
+			m.logMatches = append(m.logMatches, i)
+		}
+	}
+	m.logMatch = max(min(m.logMatch, len(m.logMatches)-1), 0)
+
+	for n, i := range m.logMatches {
+		style := styleMatch
+		if n == m.logMatch {
+			style = styleCurrentMatch
+		}
+		lines[i] = re.ReplaceAllStringFunc(lines[i], func(s string) string { return style.Render(s) })
+	}
+
+	follow := m.logViewport.AtBottom()
+	if len(lines) == 0 {
+		m.logViewport.SetContent(styleSubtext.Render("No log lines match the filter."))
+	} else {
+		m.logViewport.SetContent(strings.Join(lines, "\n"))
+	}
+	if follow {
+		m.logViewport.GotoBottom()
+	}
+}
+
+// showLogMatch scrolls the logs viewport to the current match.
+func (m *Model) showLogMatch() {
+	if len(m.logMatches) == 0 {
+		return
+	}
+	line := m.logMatches[m.logMatch]
+	if line < m.logViewport.YOffset || line >= m.logViewport.YOffset+m.logViewport.Height {
+		m.logViewport.SetYOffset(line - m.logViewport.Height/2)
+	}
+}
+
+// logsStatus describes the search and filters in effect for the logs title.
+func (m Model) logsStatus() string {
+	var parts []string
+	if f := m.logsFilter().String(); f != "" {
+		parts = append(parts, f)
+	} else if m.logSelectedOnly {
+		parts = append(parts, "no endpoint selected")
+	}
+	if m.logQuery != "" {
+		if len(m.logMatches) == 0 {
+			parts = append(parts, fmt.Sprintf("/%s no matches", m.logQuery))
+		} else {
+			parts = append(parts, fmt.Sprintf("/%s %d of %d", m.logQuery, m.logMatch+1, len(m.logMatches)))
+		}
+	}
+	return strings.Join(parts, " • ")
+}
+
+// updateLogPrompt edits the search or filter being typed in the logs panel.
+func (m Model) updateLogPrompt(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		value := strings.TrimSpace(m.logInput.Value())
+		if m.logPrompt == logPromptSearch {
+			m.logQuery, m.logMatch = value, 0
+			m.refreshLogs()
+			m.showLogMatch()
+		} else {
+			f, err := parseLogFilter(value)
+			if err != nil {
+				m.logErr = err
+				return m, nil
+			}
+			m.logFilter = f
+			m.logViewport.GotoBottom()
+			m.refreshLogs()
+		}
+		m.logPrompt, m.logErr = logPromptNone, nil
+		m.logInput.Blur()
+		return m, nil
+	case "esc":
+		m.logPrompt, m.logErr = logPromptNone, nil
+		m.logInput.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.logInput, cmd = m.logInput.Update(msg)
+	return m, cmd
+}
+
+// openLogPrompt starts typing a search or a filter, starting from the
+// current one.
+func (m *Model) openLogPrompt(prompt int) tea.Cmd {
+	m.logPrompt, m.logErr = prompt, nil
+	if prompt == logPromptSearch {
+		m.logInput.Prompt = "/"
+		m.logInput.Placeholder = "search"
+		m.logInput.SetValue(m.logQuery)
+	} else {
+		m.logInput.Prompt = "filter: "
+		m.logInput.Placeholder = "endpoint IDs and a level, e.g. 1 3 warn"
+		var fields []string
+		for _, id := range m.logFilter.Endpoints {
+			fields = append(fields, strconv.Itoa(id))
+		}
+		if m.logFilter.Level > 0 {
+			fields = append(fields, m.logFilter.Level.String())
+		}
+		m.logInput.SetValue(strings.Join(fields, " "))
+	}
+	m.logInput.CursorEnd()
+	return m.logInput.Focus()
+}
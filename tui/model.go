@@ -2,6 +2,7 @@ package tui
 
 import (
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 
 	"github.com/samaelod/nabu/engine"
@@ -15,6 +16,12 @@ const (
 	screenFilePicker
 	screenLoading
 	screenViewConfig
+	screenParams
+	screenSequence
+	screenInspector
+	screenEditor
+	screenStats
+	screenRecent
 )
 
 type sourceType int
@@ -37,20 +44,55 @@ type Model struct {
 	// Separate endpoint lists for servers and clients
 	serverEndpoints     list.Model
 	clientEndpoints     list.Model
-	activeEndpointPanel int // 0: servers, 1: clients
+	activeEndpointPanel int          // 0: servers, 1: clients
+	marked              map[int]bool // Endpoint IDs marked for bulk actions
+	confirmRemove       []int        // Endpoints to remove once x is pressed again
+	bulkErr             error        // Why the last removal failed, until the next key
 
 	width        int
 	height       int
 	selectedFile string
 
-	menuCursor int // 0: PCAP, 1: Lua
-	activeView int // 0: Endpoints List, 1: Logs Viewport
+	menuCursor int    // 0: PCAP, 1: Lua, 2: Recent
+	pickedFrom screen // Screen the scenario was chosen on, for going back
+	activeView int    // 0: Endpoints List, 1: Logs Viewport
 
 	version string
 
 	engine      *engine.Engine
+	events      *engine.Subscription // TUI subscription to engine events
 	logViewport viewport.Model
 	logContent  string // cached log content for editor
+
+	// Search and filters of the logs panel
+	logInput        textinput.Model
+	logPrompt       int // logPromptSearch or logPromptFilter while typing
+	logErr          error
+	logQuery        string
+	logMatches      []int // Lines of the logs viewport matching logQuery
+	logMatch        int   // Current entry of logMatches
+	logFilter       logFilter
+	logSelectedOnly bool // Only logs of the selected endpoint
+	logsPending     bool // A redraw of the logs panel is scheduled
+
+	params         map[string]string // Scenario parameter values from -p
+	remap          []string          // Remap rules from -remap
+	scenarioParams map[string]string // Values the current scenario was loaded with
+	paramForm      ParamForm
+
+	sequence      SequenceView // Full-screen message diagram
+	inspector     Inspector    // Payload inspector
+	inspectorBack screen       // Screen the inspector was opened from
+	editor        ScenarioEditor
+	stats         StatsView  // Live run statistics
+	statsGen      int        // Engine the stats ticks sample
+	recent        RecentView // Scenarios saved in the recent directory
+	runRecorded   bool       // The finished run is in the recent index
+
+	// Files of the loaded scenario, reloaded when one of them changes
+	watchGen    int
+	watchFiles  []string
+	watchStamps map[string]fileStamp
 }
 
 const (
@@ -59,4 +101,5 @@ const (
 	defaultListWidth = 30
 	minListWidth     = 20
 	footerHeight     = 3
+	menuItems        = 3
 )
@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/samaelod/nabu/lua"
+)
+
+// ParamForm prompts for the parameters a scenario declares before it is
+// loaded.
+type ParamForm struct {
+	Path     string // Scenario to load once submitted
+	SaveCopy bool
+	Params   []lua.Param
+	Inputs   []textinput.Model
+	Cursor   int
+	Err      error // Why the form is shown again, e.g. missing params
+}
+
+func NewParamForm(path string, saveCopy bool, params []lua.Param, err error) ParamForm {
+	f := ParamForm{Path: path, SaveCopy: saveCopy, Params: params, Err: err}
+	for _, p := range params {
+		in := textinput.New()
+		in.Prompt = ""
+		in.CharLimit = 256
+		in.Width = 40
+		in.SetValue(p.Value)
+		switch {
+		case p.Default != "":
+			in.Placeholder = p.Default
+		case p.Required:
+			in.Placeholder = "required"
+		}
+		f.Inputs = append(f.Inputs, in)
+	}
+	f.focus(0)
+	return f
+}
+
+func (f *ParamForm) focus(i int) {
+	if len(f.Inputs) == 0 {
+		return
+	}
+	f.Inputs[f.Cursor].Blur()
+	f.Cursor = (i + len(f.Inputs)) % len(f.Inputs)
+	f.Inputs[f.Cursor].Focus()
+}
+
+// Apply returns a copy of params with the entered values. Fields left empty
+// are removed, so their environment variable or default applies.
+func (f ParamForm) Apply(params map[string]string) map[string]string {
+	out := make(map[string]string, len(params)+len(f.Params))
+	for name, v := range params {
+		out[name] = v
+	}
+	for i, p := range f.Params {
+		if v := strings.TrimSpace(f.Inputs[i].Value()); v != "" {
+			out[p.Name] = v
+		} else {
+			delete(out, p.Name)
+		}
+	}
+	return out
+}
+
+// Update moves between fields and edits the focused one. Enter and esc are
+// handled by the caller.
+func (f ParamForm) Update(msg tea.Msg) (ParamForm, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "tab", "down":
+			f.focus(f.Cursor + 1)
+			return f, nil
+		case "shift+tab", "up":
+			f.focus(f.Cursor - 1)
+			return f, nil
+		}
+	}
+
+	if len(f.Inputs) == 0 {
+		return f, nil
+	}
+	var cmd tea.Cmd
+	f.Inputs[f.Cursor], cmd = f.Inputs[f.Cursor].Update(msg)
+	return f, cmd
+}
+
+func (f ParamForm) View(width int) string {
+	title := styleTitle.MarginBottom(1).Render("Parameters • " + filepath.Base(f.Path))
+
+	nameWidth := 0
+	for _, p := range f.Params {
+		nameWidth = max(nameWidth, len(p.Name)+len(p.Type)+3)
+	}
+	label := lipgloss.NewStyle().Width(nameWidth + 2)
+
+	var lines []string
+	for i, p := range f.Params {
+		name := fmt.Sprintf("%s (%s)", p.Name, p.Type)
+		if i == f.Cursor {
+			name = styleSelected.Render(name)
+		} else {
+			name = styleValue.Render(name)
+		}
+		line := label.Render(name) + f.Inputs[i].View()
+		if p.Description != "" {
+			line += "\n" + label.Render("") + styleSubtext.Render(p.Description)
+		}
+		lines = append(lines, line)
+	}
+
+	body := title + "\n" + strings.Join(lines, "\n")
+	if f.Err != nil {
+		body += "\n\n" + lipgloss.NewStyle().Foreground(colorError).Render(f.Err.Error())
+	}
+	body += "\n\n" + styleSubtext.Render("tab next • enter load • esc back")
+
+	return stylePanelTitled.
+		BorderForeground(colorSecondary).
+		Width(min(width, 100)).
+		Render(body)
+}
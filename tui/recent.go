@@ -0,0 +1,313 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/types"
+)
+
+// Lines of the panel around the rows: title, headings and status, plus
+// the panel border and padding
+const recentChromeHeight = 7
+
+type recentListedMsg struct {
+	entries []lua.RecentEntry
+	status  string
+	err     error
+}
+
+type recentOpenMsg struct{ path string }
+
+type recentClosedMsg struct{}
+
+// recentCmd runs op on the recent directory, then lists it again. status
+// describes op once it has succeeded.
+func recentCmd(dir, status string, op func() error) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if op != nil {
+			if err = op(); err != nil {
+				status = ""
+			}
+		}
+		entries, lerr := lua.ListRecent(dir)
+		if err == nil {
+			err = lerr
+		}
+		return recentListedMsg{entries: entries, status: status, err: err}
+	}
+}
+
+// recordRunCmd stores the outcome of the run of cfg in the recent index when
+// path is a scenario of the recent directory.
+func recordRunCmd(path string, cfg *types.Config, e *engine.Engine) tea.Cmd {
+	dir := lua.RecentDir()
+	if !sameDir(filepath.Dir(path), dir) || cfg == nil {
+		return nil
+	}
+	result := lua.RunResult{Time: time.Now()}
+	for _, ep := range cfg.Endpoints {
+		switch e.GetStatus(ep.ID) {
+		case types.StatusCompleted:
+			result.Completed++
+		case types.StatusError:
+			result.Errors++
+		}
+	}
+	endpoints, messages := len(cfg.Endpoints), len(cfg.Messages)
+	return func() tea.Msg {
+		// Failures only cost the history an entry
+		lua.UpdateRecent(dir, filepath.Base(path), func(entry *lua.RecentEntry) {
+			entry.LastRun = &result
+			entry.Endpoints, entry.Messages = endpoints, messages
+		})
+		return nil
+	}
+}
+
+// runOver reports whether no client of cfg is running any more. Servers
+// keep listening after a run, so they are not waited for.
+func runOver(cfg *types.Config, e *engine.Engine) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, ep := range cfg.Endpoints {
+		if ep.Kind != "server" && e.IsRunning(ep.ID) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameDir(a, b string) bool {
+	a, aerr := filepath.Abs(a)
+	b, berr := filepath.Abs(b)
+	return aerr == nil && berr == nil && a == b
+}
+
+// RecentView lists the scenarios saved in the recent directory, to reopen,
+// rename, delete or pin them.
+type RecentView struct {
+	Dir      string
+	Entries  []lua.RecentEntry
+	Cursor   int
+	Offset   int
+	Renaming bool // The name input is open
+	Status   string
+	Err      error
+
+	input   textinput.Model
+	confirm bool // Delete waits for d again
+	width   int
+	height  int
+}
+
+func NewRecentView(dir string) RecentView {
+	input := textinput.New()
+	input.Prompt = "New name: "
+	input.CharLimit = 128
+	return RecentView{Dir: dir, input: input}
+}
+
+// SetSize sets the size of the window the view is drawn in.
+func (v *RecentView) SetSize(width, height int) {
+	v.width, v.height = width, height
+	v.input.Width = max(width-20, 10)
+	v.scroll()
+}
+
+// SetEntries replaces the listed scenarios, keeping the selected one.
+func (v *RecentView) SetEntries(entries []lua.RecentEntry) {
+	var name string
+	if v.Cursor < len(v.Entries) {
+		name = v.Entries[v.Cursor].Name
+	}
+	v.Entries = entries
+	for i, entry := range entries {
+		if entry.Name == name {
+			v.Cursor = i
+		}
+	}
+	v.scroll()
+}
+
+func (v RecentView) rows() int {
+	return max(v.height-recentChromeHeight, 1)
+}
+
+func (v *RecentView) scroll() {
+	v.Cursor = max(min(v.Cursor, len(v.Entries)-1), 0)
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+v.rows() {
+		v.Offset = v.Cursor - v.rows() + 1
+	}
+	v.Offset = max(min(v.Offset, len(v.Entries)-v.rows()), 0)
+}
+
+// Update moves through the scenarios and acts on the selected one.
+// Opening one is reported with recentOpenMsg, leaving with recentClosedMsg.
+func (v RecentView) Update(msg tea.Msg) (RecentView, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	if v.Renaming {
+		switch key.String() {
+		case "enter":
+			v.Renaming = false
+			name, newName := v.Entries[v.Cursor].Name, v.input.Value()
+			return v, recentCmd(v.Dir, "Renamed "+name, func() error { return lua.RenameRecent(v.Dir, name, newName) })
+		case "esc":
+			v.Renaming = false
+			return v, nil
+		}
+		var cmd tea.Cmd
+		v.input, cmd = v.input.Update(msg)
+		return v, cmd
+	}
+
+	confirm := v.confirm
+	v.confirm = false
+
+	switch key.String() {
+	case "up", "k":
+		v.Cursor--
+	case "down", "j":
+		v.Cursor++
+	case "pgup", "b":
+		v.Cursor -= v.rows()
+	case "pgdown":
+		v.Cursor += v.rows()
+	case "home", "g":
+		v.Cursor = 0
+	case "end", "G":
+		v.Cursor = len(v.Entries) - 1
+	case "esc":
+		return v, func() tea.Msg { return recentClosedMsg{} }
+	}
+	v.scroll()
+	if len(v.Entries) == 0 {
+		return v, nil
+	}
+
+	entry := v.Entries[v.Cursor]
+	switch key.String() {
+	case "enter":
+		path := filepath.Join(v.Dir, entry.Name)
+		return v, func() tea.Msg { return recentOpenMsg{path: path} }
+	case "r":
+		v.Renaming = true
+		v.input.SetValue(strings.TrimSuffix(entry.Name, ".lua"))
+		v.input.CursorEnd()
+		return v, v.input.Focus()
+	case "d":
+		if !confirm {
+			v.confirm = true
+			v.Status, v.Err = "Press d again to delete "+entry.Name, nil
+			return v, nil
+		}
+		return v, recentCmd(v.Dir, "Deleted "+entry.Name, func() error { return lua.DeleteRecent(v.Dir, entry.Name) })
+	case "p":
+		pinned := !entry.Pinned
+		status := "Unpinned " + entry.Name
+		if pinned {
+			status = "Pinned " + entry.Name
+		}
+		return v, recentCmd(v.Dir, status, func() error {
+			return lua.UpdateRecent(v.Dir, entry.Name, func(e *lua.RecentEntry) { e.Pinned = pinned })
+		})
+	}
+	return v, nil
+}
+
+// View renders the scenarios as a table in a panel filling the window.
+func (v RecentView) View() string {
+	inner := v.width - 4 // Panel border and padding
+	const nameWidth, dateWidth, countWidth, runWidth = 28, 17, 16, 26
+	sourceWidth := max(inner-2-nameWidth-dateWidth-countWidth-runWidth, 10)
+	cell := func(s string, width int) string {
+		return lipgloss.NewStyle().Width(width).MaxWidth(width).Render(truncate(s, width-1))
+	}
+
+	heading := styleSubtext.Render("  " + cell("Name", nameWidth) + cell("Imported", dateWidth) +
+		cell("Endpoints/Msgs", countWidth) + cell("Last run", runWidth) + cell("Source", sourceWidth))
+	lines := []string{heading}
+
+	end := min(v.Offset+v.rows(), len(v.Entries))
+	for i := v.Offset; i < end; i++ {
+		entry := v.Entries[i]
+		pin := "  "
+		if entry.Pinned {
+			pin = lipgloss.NewStyle().Foreground(colorSecondary).Render("★ ")
+		}
+		counts, source := "?", "unknown"
+		if entry.Endpoints > 0 {
+			counts = fmt.Sprintf("%d / %d", entry.Endpoints, entry.Messages)
+		}
+		if entry.Source != "" {
+			source = entry.Source
+			if rel, err := filepath.Rel(".", source); err == nil && !strings.HasPrefix(rel, "..") {
+				source = rel
+			}
+		}
+
+		name := cell(entry.Name, nameWidth)
+		if i == v.Cursor {
+			name = styleSelected.Render(name)
+		}
+		row := pin + name + cell(entry.Imported.Format("2006-01-02 15:04"), dateWidth) + cell(counts, countWidth) +
+			lastRun(entry.LastRun, runWidth) + styleSubtext.Render(cell(source, sourceWidth))
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(inner).Render(row))
+	}
+	if len(v.Entries) == 0 {
+		lines = append(lines, styleSubtext.Render("No scenarios yet. Opened PCAP and Lua files are saved in "+v.Dir+"."))
+	}
+	for len(lines) < v.rows()+1 {
+		lines = append(lines, "")
+	}
+
+	var status string
+	switch {
+	case v.Renaming:
+		status = v.input.View()
+	case v.Err != nil:
+		status = lipgloss.NewStyle().Foreground(colorError).Render(truncate(v.Err.Error(), inner))
+	case v.confirm:
+		status = styleSelected.Render(v.Status)
+	default:
+		status = styleSubtext.Render(v.Status)
+	}
+	lines = append(lines, status)
+
+	title := styleTitle.Render("Recent Scenarios") + " " + styleSubtext.Render(fmt.Sprintf("%d in %s", len(v.Entries), v.Dir))
+	return stylePanelTitled.
+		BorderForeground(colorSecondary).
+		Width(v.width - 2).
+		Height(v.height - 4).
+		Render(title + "\n" + strings.Join(lines, "\n"))
+}
+
+// lastRun describes how the last run of a scenario ended.
+func lastRun(r *lua.RunResult, width int) string {
+	if r == nil {
+		return styleSubtext.Render(lipgloss.NewStyle().Width(width).Render("never run"))
+	}
+	text, style := fmt.Sprintf("✓ %d done", r.Completed), lipgloss.NewStyle().Foreground(colorSuccess)
+	if r.Errors > 0 {
+		text, style = fmt.Sprintf("✗ %d failed, %d done", r.Errors, r.Completed), lipgloss.NewStyle().Foreground(colorError)
+	}
+	when := " " + r.Time.Format("01-02 15:04")
+	return lipgloss.NewStyle().Width(width).MaxWidth(width).Render(style.Render(text) + styleSubtext.Render(when))
+}
@@ -0,0 +1,286 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/samaelod/nabu/types"
+)
+
+const (
+	seqGutterWidth = 16 // Message number and time delta
+	seqMinColWidth = 18
+	seqMaxColWidth = 32
+	// Rows used around the messages: app title, panel border and title,
+	// endpoint header and footer
+	seqChromeHeight = 7
+)
+
+// SequenceView draws every message of a scenario as an arrow between
+// endpoint columns and marks messages as the engine executes them.
+type SequenceView struct {
+	Endpoints []types.Endpoint // Columns, by ID
+	Messages  []types.Message
+	Cursor    int  // Selected message
+	Offset    int  // First message shown
+	Col       int  // First endpoint column shown
+	Follow    bool // Move the cursor to each executed message
+	Last      int  // Last executed message, -1 before a run
+
+	done     []bool
+	bySender map[int][]int // Indices into Messages per sender, in order
+	width    int
+	height   int
+}
+
+func NewSequenceView(cfg *types.Config) SequenceView {
+	v := SequenceView{
+		Endpoints: append([]types.Endpoint(nil), cfg.Endpoints...),
+		Messages:  cfg.Messages,
+		Last:      -1,
+		done:      make([]bool, len(cfg.Messages)),
+		bySender:  make(map[int][]int),
+	}
+	sort.Slice(v.Endpoints, func(i, j int) bool { return v.Endpoints[i].ID < v.Endpoints[j].ID })
+	for i, msg := range cfg.Messages {
+		v.bySender[msg.From] = append(v.bySender[msg.From], i)
+	}
+	return v
+}
+
+// SetSize sets the size of the window the view is drawn in.
+func (v *SequenceView) SetSize(width, height int) {
+	v.width, v.height = width, height
+	v.scrollTo(v.Cursor)
+}
+
+func (v SequenceView) rows() int {
+	return max(v.height-seqChromeHeight, 1)
+}
+
+// Executed marks the i-th message of sender as running. Its first message
+// starts a new pass, so the sender's earlier marks are cleared.
+func (v *SequenceView) Executed(sender, i int) {
+	idx := v.bySender[sender]
+	if i < 0 || i >= len(idx) {
+		return
+	}
+	if i == 0 {
+		for _, j := range idx {
+			v.done[j] = false
+		}
+	}
+	v.done[idx[i]] = true
+	v.Last = idx[i]
+	if v.Follow {
+		v.scrollTo(idx[i])
+	}
+}
+
+// scrollTo selects message i and scrolls it into view.
+func (v *SequenceView) scrollTo(i int) {
+	v.Cursor = max(min(i, len(v.Messages)-1), 0)
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+v.rows() {
+		v.Offset = v.Cursor - v.rows() + 1
+	}
+}
+
+// Update scrolls the diagram. Esc is handled by the caller.
+func (v SequenceView) Update(msg tea.Msg) SequenceView {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v
+	}
+	switch key.String() {
+	case "up", "k":
+		v.scrollTo(v.Cursor - 1)
+	case "down", "j":
+		v.scrollTo(v.Cursor + 1)
+	case "pgup", "b":
+		v.scrollTo(v.Cursor - v.rows())
+	case "pgdown", " ":
+		v.scrollTo(v.Cursor + v.rows())
+	case "home", "g":
+		v.scrollTo(0)
+	case "end", "G":
+		v.scrollTo(len(v.Messages) - 1)
+	case "left", "h":
+		v.Col = max(v.Col-1, 0)
+	case "right", "l":
+		v.Col = min(v.Col+1, max(len(v.Endpoints)-1, 0))
+	case "f":
+		v.Follow = !v.Follow
+		if v.Follow && v.Last >= 0 {
+			v.scrollTo(v.Last)
+		}
+	}
+	return v
+}
+
+// columns returns the width of each endpoint column and how many fit in width.
+func (v SequenceView) columns(width int) (colWidth, visible int) {
+	avail := width - seqGutterWidth
+	if len(v.Endpoints) == 0 {
+		return seqMinColWidth, 0
+	}
+	colWidth = min(max(avail/len(v.Endpoints), seqMinColWidth), seqMaxColWidth)
+	visible = min(max(avail/colWidth, 1), len(v.Endpoints)-v.Col)
+	return colWidth, visible
+}
+
+// View renders the diagram as a panel filling the window.
+func (v SequenceView) View() string {
+	inner := v.width - 4 // Panel border and padding
+	colWidth, visible := v.columns(inner)
+	cols := v.Endpoints[v.Col : v.Col+visible]
+
+	// Column centre of each visible endpoint
+	centre := make(map[int]int, len(cols))
+	for i, ep := range cols {
+		centre[ep.ID] = seqGutterWidth + i*colWidth + colWidth/2
+	}
+	lineWidth := seqGutterWidth + visible*colWidth
+
+	header := func(text func(types.Endpoint) string) string {
+		line := []rune(strings.Repeat(" ", lineWidth))
+		for i, ep := range cols {
+			s := []rune(truncate(text(ep), colWidth-1))
+			start := seqGutterWidth + i*colWidth + (colWidth-len(s))/2
+			copy(line[start:], s)
+		}
+		return string(line)
+	}
+
+	lines := []string{
+		styleSelected.Render(header(func(ep types.Endpoint) string { return fmt.Sprintf("[%d] %s", ep.ID, ep.Kind) })),
+		styleSubtext.Render(header(func(ep types.Endpoint) string { return fmt.Sprintf("%s:%d", ep.Address, ep.Port) })),
+	}
+
+	end := min(v.Offset+v.rows(), len(v.Messages))
+	for i := v.Offset; i < end; i++ {
+		line := v.arrow(i, centre, lineWidth, seqGutterWidth, lineWidth-1)
+
+		style := styleSubtext
+		switch {
+		case i == v.Last:
+			style = lipgloss.NewStyle().Foreground(colorSuccess).Bold(true)
+		case v.done[i]:
+			style = styleValue
+		}
+		if i == v.Cursor {
+			style = style.Reverse(true)
+		}
+		lines = append(lines, style.Render(line))
+	}
+	if len(v.Messages) == 0 {
+		lines = append(lines, styleSubtext.Render("No messages in this scenario."))
+	}
+
+	position := fmt.Sprintf("%d/%d", min(v.Cursor+1, len(v.Messages)), len(v.Messages))
+	if v.Col > 0 || v.Col+visible < len(v.Endpoints) {
+		position += fmt.Sprintf(" • endpoints %d-%d of %d", v.Col+1, v.Col+visible, len(v.Endpoints))
+	}
+	title := styleTitle.Render("Sequence") + " " + styleSubtext.Render(position)
+
+	return stylePanelTitled.
+		BorderForeground(colorSecondary).
+		Width(v.width - 2).
+		Height(v.rows() + 3).
+		Render(title + "\n" + strings.Join(lines, "\n"))
+}
+
+// arrow draws message i on the lifelines. Endpoints scrolled out of view are
+// drawn at the left or right edge.
+func (v SequenceView) arrow(i int, centre map[int]int, width, left, right int) string {
+	msg := v.Messages[i]
+	line := []rune(strings.Repeat(" ", width))
+	for _, c := range centre {
+		line[c] = '│'
+	}
+	gutter := []rune(fmt.Sprintf("%5d %+6dms", i+1, msg.TDelta))
+	copy(line, gutter)
+
+	label := msg.Kind
+	if n := len(msg.Value) / 2; n > 0 {
+		label += fmt.Sprintf(" %dB", n)
+	}
+
+	pos := func(id int) (int, bool) {
+		if c, ok := centre[id]; ok {
+			return c, true
+		}
+		// Off screen: before or after the visible columns
+		switch j := v.index(id); {
+		case j < 0:
+			return 0, false
+		case j < v.Col:
+			return left, true
+		}
+		return right, true
+	}
+	from, okFrom := pos(msg.From)
+	to, okTo := pos(msg.To)
+
+	_, fromShown := centre[msg.From]
+	_, toShown := centre[msg.To]
+
+	switch {
+	case !okFrom || !fromShown && !toShown:
+		copy(line[left:], []rune(truncate(fmt.Sprintf(" %d → %d %s", msg.From, msg.To, label), width-left)))
+		return string(line)
+	case !okTo:
+		copy(line[min(from+1, width-1):], []rune(truncate(fmt.Sprintf(" %s → %d?", label, msg.To), width-from-1)))
+		return string(line)
+	}
+
+	lo, hi := min(from, to), max(from, to)
+	for c := lo + 1; c < hi; c++ {
+		line[c] = '─'
+	}
+	if to > from {
+		line[to-1] = '▶'
+	} else {
+		line[to+1] = '◀'
+	}
+
+	// Label over the shaft, or past the arrow if it doesn't fit
+	text := []rune(" " + label + " ")
+	past := hi + 1
+	if from == to {
+		past++ // The arrowhead of a message to itself is right of the lifeline
+	}
+	if span := hi - lo - 3; len(text) <= span {
+		copy(line[lo+2+(span-len(text))/2:], text)
+	} else if past < width {
+		copy(line[past:], []rune(truncate(string(text), width-past)))
+	}
+	return string(line)
+}
+
+func (v SequenceView) index(id int) int {
+	for i, ep := range v.Endpoints {
+		if ep.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// truncate shortens s to n runes, ending in "…" when cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 {
+		return ""
+	}
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,290 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/samaelod/nabu/engine"
+	"github.com/samaelod/nabu/types"
+)
+
+const (
+	statsInterval = time.Second
+	statsHistory  = 120 // Rate samples kept for the sparklines
+	statsBarWidth = 20
+	// Lines of the panel above the endpoint rows: title, summary and
+	// headings, plus one for the load profile
+	statsHeaderLines = 9
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// statsTickMsg asks for a new sample; gen ties it to the engine sampled, so
+// ticks for a replaced engine stop.
+type statsTickMsg struct {
+	gen int
+}
+
+func statsTickCmd(gen int) tea.Cmd {
+	return tea.Tick(statsInterval, func(time.Time) tea.Msg { return statsTickMsg{gen: gen} })
+}
+
+// StatsView shows live counters of a run: connections, message and byte
+// rates, response latencies and how far each endpoint has got.
+type StatsView struct {
+	Endpoints []types.Endpoint // Rows, by ID
+	Current   engine.RunStats
+	Load      engine.LoadStats
+	HasLoad   bool
+	Status    map[int]types.EndpointStatus
+	Metrics   map[int]engine.EndpointMetrics
+	Latency   [3]time.Duration // Response p50, p90 and p99
+	LoadP99   time.Duration    // Load session p99
+	MsgRates  []float64        // Messages/s per sample, oldest first
+	ByteRates []float64        // Bytes sent and received per second
+	Offset    int              // First endpoint row shown
+
+	sampled time.Time
+	width   int
+	height  int
+}
+
+func NewStatsView(cfg *types.Config) StatsView {
+	var v StatsView
+	v.SetScenario(cfg)
+	return v
+}
+
+// SetScenario replaces the endpoint rows, keeping the rate history.
+func (v *StatsView) SetScenario(cfg *types.Config) {
+	v.Endpoints = append([]types.Endpoint(nil), cfg.Endpoints...)
+	sort.Slice(v.Endpoints, func(i, j int) bool { return v.Endpoints[i].ID < v.Endpoints[j].ID })
+	v.scroll(v.Offset)
+}
+
+// SetSize sets the size of the window the view is drawn in.
+func (v *StatsView) SetSize(width, height int) {
+	v.width, v.height = width, height
+	v.scroll(v.Offset)
+}
+
+func (v StatsView) rows() int {
+	header := statsHeaderLines
+	if v.HasLoad {
+		header++
+	}
+	return max(v.height-4-header, 1)
+}
+
+func (v *StatsView) scroll(offset int) {
+	v.Offset = max(min(offset, len(v.Endpoints)-v.rows()), 0)
+}
+
+// Sample reads the engine's counters and records the rates since the
+// previous sample.
+func (v *StatsView) Sample(e *engine.Engine, now time.Time) {
+	st := e.Stats()
+	if !v.sampled.IsZero() {
+		secs := now.Sub(v.sampled).Seconds()
+		bytes := st.BytesSent + st.BytesRecv - v.Current.BytesSent - v.Current.BytesRecv
+		v.MsgRates = appendRate(v.MsgRates, float64(st.Messages-v.Current.Messages)/secs)
+		v.ByteRates = appendRate(v.ByteRates, float64(bytes)/secs)
+	}
+	v.Current, v.sampled = st, now
+	for i, p := range []float64{50, 90, 99} {
+		v.Latency[i] = engine.Percentile(st.Latencies, p)
+	}
+
+	v.Load = e.LoadStats()
+	v.LoadP99 = engine.Percentile(v.Load.Latencies, 99)
+	v.HasLoad = e.HasLoadProfile()
+	v.Status = make(map[int]types.EndpointStatus, len(v.Endpoints))
+	v.Metrics = make(map[int]engine.EndpointMetrics, len(v.Endpoints))
+	for _, ep := range v.Endpoints {
+		v.Status[ep.ID] = e.GetStatus(ep.ID)
+		v.Metrics[ep.ID] = e.Metrics(ep.ID)
+	}
+}
+
+func appendRate(rates []float64, r float64) []float64 {
+	rates = append(rates, max(r, 0))
+	if len(rates) > statsHistory {
+		rates = rates[len(rates)-statsHistory:]
+	}
+	return rates
+}
+
+// Update scrolls the endpoint rows. Esc is handled by the caller.
+func (v StatsView) Update(msg tea.Msg) StatsView {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v
+	}
+	switch key.String() {
+	case "up", "k":
+		v.scroll(v.Offset - 1)
+	case "down", "j":
+		v.scroll(v.Offset + 1)
+	case "pgup", "b":
+		v.scroll(v.Offset - v.rows())
+	case "pgdown", " ":
+		v.scroll(v.Offset + v.rows())
+	case "home", "g":
+		v.scroll(0)
+	case "end", "G":
+		v.scroll(len(v.Endpoints))
+	}
+	return v
+}
+
+// View renders the dashboard as a panel filling the window.
+func (v StatsView) View() string {
+	inner := v.width - 4 // Panel border and padding
+	label := lipgloss.NewStyle().Foreground(colorSubtext).Width(14)
+	line := func(name, value, spark string) string {
+		value = lipgloss.NewStyle().Width(30).Render(value)
+		return label.Render(name) + value + lipgloss.NewStyle().Foreground(colorSecondary).Render(spark)
+	}
+	sparkWidth := max(inner-14-30, 0)
+
+	errors := styleValue.Render("0")
+	if v.Current.Errors > 0 {
+		errors = lipgloss.NewStyle().Foreground(colorError).Render(fmt.Sprint(v.Current.Errors))
+	}
+
+	latency := styleSubtext.Render("no responses yet")
+	if n := len(v.Current.Latencies); n > 0 {
+		latency = fmt.Sprintf("p50 %s • p90 %s • p99 %s",
+			formatLatency(v.Latency[0]), formatLatency(v.Latency[1]), formatLatency(v.Latency[2]))
+		latency += styleSubtext.Render(fmt.Sprintf(" (%d responses)", n))
+	}
+
+	lines := []string{
+		"",
+		label.Render("Connections") + fmt.Sprintf("%d open", v.Current.Connections),
+		line("Messages", fmt.Sprintf("%d • %.1f/s", v.Current.Messages, last(v.MsgRates)), sparkline(v.MsgRates, sparkWidth)),
+		line("Traffic", fmt.Sprintf("↑%s ↓%s • %s/s", formatBytes(float64(v.Current.BytesSent)),
+			formatBytes(float64(v.Current.BytesRecv)), formatBytes(last(v.ByteRates))), sparkline(v.ByteRates, sparkWidth)),
+		label.Render("Latency") + latency,
+		label.Render("Errors") + errors,
+	}
+	if v.HasLoad {
+		load := styleSubtext.Render("not running")
+		if v.Load.Running || v.Load.Started > 0 {
+			load = fmt.Sprintf("%.1f/s • %d active • %d done • %d failed • %d dropped",
+				v.Load.Rate, v.Load.Active, v.Load.Completed, v.Load.Failed, v.Load.Dropped)
+			if len(v.Load.Latencies) > 0 {
+				load += fmt.Sprintf(" • p99 %s", formatLatency(v.LoadP99))
+			}
+		}
+		lines = append(lines, label.Render("Load")+truncate(load, max(inner-14, 0)))
+	}
+	lines = append(lines, "", styleSelected.Render("Endpoints"))
+
+	end := min(v.Offset+v.rows(), len(v.Endpoints))
+	for _, ep := range v.Endpoints[v.Offset:end] {
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(inner).Render(v.endpointRow(ep)))
+	}
+	if len(v.Endpoints) == 0 {
+		lines = append(lines, styleSubtext.Render("No endpoints in this scenario."))
+	}
+
+	title := styleTitle.Render("Statistics")
+	if v.Offset > 0 || end < len(v.Endpoints) {
+		title += " " + styleSubtext.Render(fmt.Sprintf("endpoints %d-%d of %d", v.Offset+1, end, len(v.Endpoints)))
+	}
+
+	return stylePanelTitled.
+		BorderForeground(colorSecondary).
+		Width(v.width - 2).
+		Height(v.height - 4).
+		Render(title + "\n" + strings.Join(lines, "\n"))
+}
+
+// endpointRow shows an endpoint's status and, for endpoints that send,
+// a bar of the messages executed in the current pass.
+func (v StatsView) endpointRow(ep types.Endpoint) string {
+	status := "idle"
+	style := styleSubtext
+	switch v.Status[ep.ID] {
+	case types.StatusRunning:
+		status, style = "running", lipgloss.NewStyle().Foreground(colorSecondary)
+	case types.StatusCompleted:
+		status, style = "done", lipgloss.NewStyle().Foreground(colorSuccess)
+	case types.StatusError:
+		status, style = "error", lipgloss.NewStyle().Foreground(colorError)
+	}
+	row := fmt.Sprintf("%-5s %-7s ", fmt.Sprintf("[%d]", ep.ID), ep.Kind) + style.Render(fmt.Sprintf("%-8s", status))
+
+	p := v.Current.Progress[ep.ID]
+	if p.Total == 0 {
+		return row + styleSubtext.Render(" no messages to send")
+	}
+	filled := p.Message * statsBarWidth / p.Total
+	row += " " + style.Render(strings.Repeat("█", filled)) + styleSubtext.Render(strings.Repeat("░", statsBarWidth-filled)) +
+		fmt.Sprintf(" %d/%d", p.Message, p.Total)
+
+	if m := v.Metrics[ep.ID]; m.Iterations > 0 {
+		row += styleSubtext.Render(fmt.Sprintf(" • %d passes", m.Iterations))
+		if m.Errors > 0 {
+			row += lipgloss.NewStyle().Foreground(colorError).Render(fmt.Sprintf(" • %d errors", m.Errors))
+		}
+	}
+	return row
+}
+
+// sparkline draws the last width values scaled to the largest of them.
+func sparkline(values []float64, width int) string {
+	if width <= 0 || len(values) == 0 {
+		return ""
+	}
+	values = values[max(len(values)-width, 0):]
+	top := 0.0
+	for _, x := range values {
+		top = max(top, x)
+	}
+	out := make([]rune, len(values))
+	for i, x := range values {
+		j := 0
+		if top > 0 {
+			j = int(x / top * float64(len(sparkBlocks)-1))
+		}
+		out[i] = sparkBlocks[j]
+	}
+	return string(out)
+}
+
+func last(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1]
+}
+
+func formatBytes(n float64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -63,7 +63,7 @@ var (
 			Padding(1, 4).
 			Margin(0, 1).
 			Align(lipgloss.Center).
-			Width(24)
+			Width(20) // Three cards fit the narrowest window
 
 	styleMenuItemSelected = styleMenuItem.
 				Foreground(colorText).
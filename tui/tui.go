@@ -1,26 +1,55 @@
 package tui
 
 import (
+	"path/filepath"
+	"strings"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func New(version string) Model {
+// Options configures how the TUI starts.
+type Options struct {
+	Scenario string            // Open this scenario instead of the source menu
+	Params   map[string]string // Scenario parameter values
+	Remap    []string          // Remap rules tried before those of nabu.json
+}
+
+func New(version string, opts Options) Model {
 	fb := NewFileBrowser([]string{".pcap", ".pcapng", ".cap", ".lua"})
 
-	return Model{
+	m := Model{
 		screen:      screenSourceSelect,
 		fileBrowser: fb,
 		menuCursor:  0,
+		pickedFrom:  screenFilePicker,
 		version:     version,
+		params:      opts.Params,
+		remap:       opts.Remap,
+		logInput:    newLogInput(),
+		marked:      make(map[int]bool),
+
+		scenarioParams: opts.Params,
+	}
+	if opts.Scenario != "" {
+		m.screen = screenLoading
+		m.selectedFile = opts.Scenario
+		m.source = sourceLua
+		if !strings.EqualFold(filepath.Ext(opts.Scenario), ".lua") {
+			m.source = sourcePCAP
+		}
 	}
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
+	if m.screen == screenLoading {
+		return openScenarioCmd(m.source, m.selectedFile, true, m.scenarioOptions(m.params))
+	}
 	return nil
 }
 
-func Run(version string) error {
-	p := tea.NewProgram(New(version), tea.WithAltScreen())
+func Run(version string, opts Options) error {
+	p := tea.NewProgram(New(version, opts), tea.WithAltScreen())
 	_, err := p.Run()
 	return err
 }
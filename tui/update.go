@@ -1,22 +1,26 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/samaelod/nabu/config"
 	"github.com/samaelod/nabu/engine"
 	"github.com/samaelod/nabu/lua"
-	"github.com/samaelod/nabu/pcapreader"
 	"github.com/samaelod/nabu/types"
 )
 
@@ -90,6 +94,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.fileBrowser.SetSize(listWidth-4, msg.Height-7)
+		m.sequence.SetSize(msg.Width-4, msg.Height-4)
+		m.inspector.SetSize(msg.Width-4, msg.Height-4)
+		m.editor.SetSize(msg.Width-4, msg.Height-4)
+		m.stats.SetSize(msg.Width-4, msg.Height-4)
+		m.recent.SetSize(msg.Width-4, msg.Height-4)
 		if m.screen == screenViewConfig {
 			// Set size for both endpoint lists
 			listHeight := (msg.Height - 7) / 2
@@ -127,13 +136,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.KeyMsg:
-		if msg.String() == "ctrl+c" || msg.String() == "q" {
+		// q is typed into forms and the payload search, and the scenario
+		// editor is left with esc so unsaved changes are not lost
+		typing := m.screen == screenParams || m.screen == screenEditor ||
+			(m.screen == screenInspector && m.inspector.Searching) ||
+			(m.screen == screenRecent && m.recent.Renaming) ||
+			(m.screen == screenViewConfig && m.logPrompt != logPromptNone) ||
+			(m.screen == screenViewConfig && m.activeView == 0 && m.activeEndpointList().FilterState() == list.Filtering)
+		if msg.String() == "ctrl+c" || (msg.String() == "q" && !typing) {
 			return m, tea.Quit
 		}
 	}
 
 	// Handle global messages (like config loaded) regardless of screen
 	switch msg := msg.(type) {
+	case paramsPromptMsg:
+		m.paramForm = NewParamForm(msg.path, msg.saveCopy, msg.params, msg.err)
+		m.screen = screenParams
+		return m, textinput.Blink
+
 	case configLoadedMsg:
 		m.config = msg.config
 		if msg.path != "" {
@@ -141,44 +162,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			setupSessionLog(msg.path)
 		}
 
-		var servers, clients []types.Endpoint
-		for _, ep := range m.config.Endpoints {
-			if ep.Kind == "server" {
-				servers = append(servers, ep)
-			} else {
-				clients = append(clients, ep)
-			}
-		}
-		sort.Slice(servers, func(i, j int) bool { return servers[i].ID < servers[j].ID })
-		sort.Slice(clients, func(i, j int) bool { return clients[i].ID < clients[j].ID })
-
-		// Create server list
-		serverItems := []list.Item{}
-		for _, ep := range servers {
-			serverItems = append(serverItems, endpointItem(ep))
-		}
-		m.serverEndpoints = list.New(
-			serverItems,
-			endpointsDelegate{},
-			30,
-			(m.height-7)/2,
-		)
-		m.serverEndpoints.SetShowHelp(false)
-		m.serverEndpoints.SetShowTitle(false)
-
-		// Create client list
-		clientItems := []list.Item{}
-		for _, ep := range clients {
-			clientItems = append(clientItems, endpointItem(ep))
-		}
-		m.clientEndpoints = list.New(
-			clientItems,
-			endpointsDelegate{},
-			30,
-			(m.height-7)/2,
-		)
-		m.clientEndpoints.SetShowHelp(false)
-		m.clientEndpoints.SetShowTitle(false)
+		m.setEndpointLists()
+		m.sequence = NewSequenceView(m.config)
+		m.sequence.SetSize(m.width-4, m.height-4)
 
 		// Default to servers panel
 		m.activeEndpointPanel = 0
@@ -196,13 +182,33 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		logPath := ""
+		transcriptDir := ""
+		capturePath := ""
 		if msg.path != "" {
 			baseName := filepath.Base(msg.path)
 			ext := filepath.Ext(baseName)
 			nameWithoutExt := strings.TrimSuffix(baseName, ext)
 			logPath = filepath.Join(logsDir, nameWithoutExt+".log")
+			transcriptDir = filepath.Join(logsDir, nameWithoutExt)
+			if appConfig.Capture != "" {
+				capturePath = filepath.Join(logsDir, nameWithoutExt+"."+appConfig.Capture)
+			}
+		}
+		// Release the previous engine before replacing it
+		if m.engine != nil {
+			m.engine.StopAll()
+			m.engine.Close()
+		}
+		log := engine.NewLoggerWithOptions(engine.LoggerOptionsFor(appConfig, logPath))
+		m.engine = engine.NewEngineWithLogger(m.config, log, m.config.Globals.Timeout, m.config.Globals.Delay)
+		if appConfig.Verbose && transcriptDir != "" {
+			m.engine.EnableTranscripts(transcriptDir)
 		}
-		m.engine = engine.NewEngine(m.config, logPath, appConfig.LogLines, m.config.Globals.Timeout, m.config.Globals.Delay)
+		if capturePath != "" {
+			// Failures are reported in the engine log
+			m.engine.EnableCapture(capturePath)
+		}
+		m.events = m.engine.Events.Subscribe(256, engine.DropOldest)
 
 		m.screen = screenViewConfig
 
@@ -212,26 +218,171 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.logViewport.SetContent("Ready to run simulation...")
 		m.logContent = "Ready to run simulation..."
 
-		return m, nil
+		m.stats = NewStatsView(m.config)
+		m.stats.SetSize(m.width-4, m.height-4)
+		m.stats.Sample(m.engine, time.Now())
+		m.statsGen++
+
+		return m, tea.Batch(waitForEvent(m.events), m.watchScenario(), statsTickCmd(m.statsGen))
 
 	case editorFinishedMsg:
-		// Stop all running endpoints before reloading config
-		if m.engine != nil {
-			m.engine.StopAll()
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
 		}
+		return m, reloadCmd(m.scenarioSource(), m.scenarioOptions(m.scenarioParams), false)
+
+	case editorOpenedMsg:
 		if msg.err != nil {
 			m.err = msg.err
+			m.screen = screenLoading
+			return m, nil
+		}
+		selected := -1
+		if ep, ok := m.selectedEndpoint(); ok {
+			selected = ep.ID
+		}
+		m.editor = NewScenarioEditor(msg.path, msg.config, msg.saveAs, selected)
+		m.editor.SetSize(m.width-4, m.height-4)
+		m.screen = screenEditor
+		return m, nil
+
+	case scenarioSavedMsg:
+		if msg.err != nil {
+			m.editor.Status, m.editor.Err = "", fmt.Errorf("save failed: %w", msg.err)
+			return m, nil
+		}
+		m.editor.Dirty = false
+		m.editor.Status = "Saved " + msg.path
+		if msg.saveAs != "" {
+			// Later saves go to the new file, which holds everything
+			m.editor.Status = fmt.Sprintf("Saved as %s, %s is unchanged because %s", msg.path, m.editor.Path, msg.saveAs)
+			m.editor.Path, m.editor.SaveAs = msg.path, ""
+		}
+		// A new file holds the parameter values the scenario was read with,
+		// and one saved in place declares none
+		m.scenarioParams = nil
+		// Reload here rather than through the watcher, which would reload
+		// only once the editor is closed
+		m.watchStamps = stampFiles(m.watchFiles)
+		return m, reloadCmd(msg.path, m.scenarioOptions(nil), false)
+
+	case endpointsRemovedMsg:
+		if msg.err != nil {
+			m.bulkErr = fmt.Errorf("remove failed: %w", msg.err)
+			return m, nil
+		}
+		for _, id := range msg.ids {
+			delete(m.marked, id)
+		}
+		return m, func() tea.Msg { return reloadedMsg{config: msg.config} }
+
+	case captureSummaryMsg:
+		// Summaries finishing after a file was chosen are kept for later
+		m.fileBrowser, _ = m.fileBrowser.Update(msg)
+		return m, nil
+
+	case recentListedMsg:
+		m.recent.SetEntries(msg.entries)
+		m.recent.Status, m.recent.Err = msg.status, msg.err
+		return m, nil
+
+	case recentOpenMsg:
+		// Reopen the saved scenario itself rather than a new copy of it
+		m.source = sourceLua
+		m.selectedFile = msg.path
+		m.scenarioParams = m.params
+		m.pickedFrom = screenRecent
+		m.screen = screenLoading
+		return m, openScenarioCmd(sourceLua, msg.path, false, m.scenarioOptions(m.params))
+
+	case recentClosedMsg:
+		m.screen = screenSourceSelect
+		return m, nil
+
+	case editorClosedMsg:
+		m.screen = screenViewConfig
+		return m, nil
+
+	case watchMsg:
+		// Drop checks for a scenario that has been replaced since
+		if msg.gen != m.watchGen {
+			return m, nil
+		}
+		// Keep the change pending until the scenario is shown again
+		if msg.changed == "" || m.screen != screenViewConfig || m.engine == nil {
+			return m, watchCmd(m.watchGen, m.watchStamps)
+		}
+		m.watchStamps = stampFiles(m.watchFiles)
+		return m, tea.Batch(
+			watchCmd(m.watchGen, m.watchStamps),
+			reloadCmd(m.scenarioSource(), m.scenarioOptions(m.scenarioParams), true),
+		)
+
+	case reloadedMsg:
+		if m.engine == nil {
 			return m, nil
 		}
-		return m, loadConfigCmd(sourceLua, m.selectedFile, false)
+		if _, ok := m.engine.Reload(msg.config); !ok {
+			// Globals changed, so start over with a new engine
+			path := m.selectedFile
+			return m, func() tea.Msg { return configLoadedMsg{config: msg.config, path: path} }
+		}
+		m.config = msg.config
+		m.setEndpointLists()
+		follow := m.sequence.Follow
+		m.sequence = NewSequenceView(m.config)
+		m.sequence.Follow = follow
+		m.sequence.SetSize(m.width-4, m.height-4)
+		m.stats.SetScenario(m.config)
+		return m, m.watchScenario()
+
+	case statsTickMsg:
+		if msg.gen != m.statsGen || m.engine == nil {
+			return m, nil
+		}
+		m.stats.Sample(m.engine, time.Now())
+		return m, statsTickCmd(m.statsGen)
 
-	case logMsg:
-		// Get all logs from engine logger (handles file I/O internally)
-		if m.engine != nil && m.engine.Log != nil {
-			m.logContent = m.engine.Log.ReadAll()
-			m.logViewport.SetContent(m.logContent)
-			m.logViewport.GotoBottom()
-			return m, waitForLog(m.engine.Log)
+	case reloadFailedMsg:
+		if msg.auto && m.engine != nil {
+			m.engine.ReloadFailed(msg.err)
+			return m, nil
+		}
+		// Show the loading screen so problems in the scenario are listed
+		m.err = msg.err
+		m.screen = screenLoading
+		return m, nil
+
+	case eventMsg:
+		// Ignore events still in flight from a replaced engine
+		if msg.sub != m.events {
+			return m, nil
+		}
+		if msg.event.Type == engine.EventMessage {
+			m.sequence.Executed(msg.event.Endpoint, msg.event.Message)
+			return m, waitForEvent(m.events)
+		}
+		redraw := m.scheduleLogs()
+		// Keep how the run went in the history of recent scenarios, once
+		// the last client stopped
+		if ev := msg.event; ev.Type == engine.EventStatus {
+			switch ev.Status {
+			case types.StatusRunning:
+				m.runRecorded = false
+			case types.StatusCompleted, types.StatusError:
+				if !m.runRecorded && runOver(m.config, m.engine) {
+					m.runRecorded = true
+					return m, tea.Batch(waitForEvent(m.events), redraw, recordRunCmd(m.selectedFile, m.config, m.engine))
+				}
+			}
+		}
+		return m, tea.Batch(waitForEvent(m.events), redraw)
+
+	case logsTickMsg:
+		m.logsPending = false
+		if msg.gen == m.statsGen {
+			m.refreshLogs()
 		}
 		return m, nil
 	}
@@ -242,17 +393,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg, ok := msg.(tea.KeyMsg); ok {
 			switch msg.String() {
 			case "up", "k", "left", "h":
-				m.menuCursor--
-				if m.menuCursor < 0 {
-					m.menuCursor = 1
-				}
+				m.menuCursor = (m.menuCursor + menuItems - 1) % menuItems
 			case "down", "j", "right", "l":
-				m.menuCursor++
-				if m.menuCursor > 1 {
-					m.menuCursor = 0
-				}
+				m.menuCursor = (m.menuCursor + 1) % menuItems
 			case "enter":
 				switch m.menuCursor {
+				case 2:
+					m.recent = NewRecentView(lua.RecentDir())
+					m.recent.SetSize(m.width-4, m.height-4)
+					m.screen = screenRecent
+					return m, recentCmd(m.recent.Dir, "", nil)
 				case 0:
 					m.source = sourcePCAP
 					m.fileBrowser = NewFileBrowser([]string{".pcap", ".cap", ".pcapng"})
@@ -265,7 +415,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				listWidth := m.width / 3
 				m.fileBrowser.SetSize(listWidth-4, m.height-7) // Adjusted for split view
 				m.screen = screenFilePicker
-				return m, nil // Browser doesn't need Init cmd
+				// Summarize the first entry if it is a capture
+				return m, m.fileBrowser.summarize()
 			}
 		}
 		return m, nil
@@ -303,7 +454,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				path := fi.path
 				m.screen = screenLoading
 				log.Println("\n  You selected: " + path + "\n")
-				return m, loadConfigCmd(m.source, path, true)
+				m.scenarioParams = m.params
+				m.pickedFrom = screenFilePicker
+				return m, openScenarioCmd(m.source, path, true, m.scenarioOptions(m.params))
 			}
 		}
 
@@ -311,45 +464,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case screenLoading:
 		switch msg := msg.(type) {
-		case loadedMsg:
-			var servers, clients []types.Endpoint
-			for _, ep := range m.config.Endpoints {
-				if ep.Kind == "server" {
-					servers = append(servers, ep)
+		case tea.KeyMsg:
+			if msg.String() == "esc" && m.err != nil {
+				m.err = nil
+				// Back to the running scenario when reloading, else to where
+				// the scenario was picked
+				if m.engine != nil {
+					m.screen = screenViewConfig
 				} else {
-					clients = append(clients, ep)
+					m.screen = m.pickedFrom
 				}
 			}
-			sort.Slice(servers, func(i, j int) bool { return servers[i].ID < servers[j].ID })
-			sort.Slice(clients, func(i, j int) bool { return clients[i].ID < clients[j].ID })
-
-			// Create server list
-			serverItems := []list.Item{}
-			for _, ep := range servers {
-				serverItems = append(serverItems, endpointItem(ep))
-			}
-			m.serverEndpoints = list.New(
-				serverItems,
-				endpointsDelegate{},
-				30,
-				(m.height-2)/2,
-			)
-			m.serverEndpoints.SetShowTitle(false)
-			m.serverEndpoints.SetShowHelp(false)
-
-			// Create client list
-			clientItems := []list.Item{}
-			for _, ep := range clients {
-				clientItems = append(clientItems, endpointItem(ep))
-			}
-			m.clientEndpoints = list.New(
-				clientItems,
-				endpointsDelegate{},
-				30,
-				(m.height-2)/2,
-			)
-			m.clientEndpoints.SetShowTitle(false)
-			m.clientEndpoints.SetShowHelp(false)
+		case loadedMsg:
+			m.setEndpointLists()
 
 			// Default to servers panel
 			m.activeEndpointPanel = 0
@@ -357,14 +484,105 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case errMsg:
 			m.err = msg.err
 		}
+
+	case screenParams:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "enter":
+				m.scenarioParams = m.paramForm.Apply(m.scenarioParams)
+				m.screen = screenLoading
+				return m, loadConfigCmd(m.paramForm.Path, m.paramForm.SaveCopy, m.scenarioOptions(m.scenarioParams))
+			case "esc":
+				// Back to the running scenario when reloading, else to where
+				// the scenario was picked
+				if m.engine != nil {
+					m.screen = screenViewConfig
+				} else {
+					m.screen = m.pickedFrom
+				}
+				return m, nil
+			}
+		}
+		var cmd tea.Cmd
+		m.paramForm, cmd = m.paramForm.Update(msg)
+		return m, cmd
+
+	case screenSequence:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "esc", "t":
+				m.screen = screenViewConfig
+				return m, nil
+			case "enter":
+				// Inspect the selected message among all of them
+				indices := make([]int, len(m.config.Messages))
+				for i := range indices {
+					indices[i] = i
+				}
+				m.openInspector(indices, m.sequence.Cursor)
+				return m, nil
+			}
+		}
+		m.sequence = m.sequence.Update(msg)
+		return m, nil
+
+	case screenStats:
+		if msg, ok := msg.(tea.KeyMsg); ok && (msg.String() == "esc" || msg.String() == "d") {
+			m.screen = screenViewConfig
+			return m, nil
+		}
+		m.stats = m.stats.Update(msg)
+		return m, nil
+
+	case screenEditor:
+		var cmd tea.Cmd
+		m.editor, cmd = m.editor.Update(msg)
+		return m, cmd
+
+	case screenRecent:
+		var cmd tea.Cmd
+		m.recent, cmd = m.recent.Update(msg)
+		return m, cmd
+
+	case screenInspector:
+		if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "esc" && !m.inspector.Searching {
+			m.screen = m.inspectorBack
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.inspector, cmd = m.inspector.Update(msg)
+		return m, cmd
 	}
 
 	if m.screen == screenViewConfig {
 		var cmd tea.Cmd
 		var cmds []tea.Cmd
 
+		if msg, ok := msg.(tea.KeyMsg); ok && m.logPrompt != logPromptNone {
+			return m.updateLogPrompt(msg)
+		}
+
+		// Keys are typed into the endpoint filter while it is open
+		filtering := m.activeView == 0 && m.activeEndpointList().FilterState() == list.Filtering
+
+		if msg, ok := msg.(tea.KeyMsg); ok && m.activeView == 0 && !filtering {
+			m.bulkErr = nil
+			if ids := m.confirmRemove; len(ids) > 0 {
+				m.confirmRemove = nil
+				if msg.String() != "x" {
+					return m, nil
+				}
+				if m.engine != nil {
+					for _, id := range ids {
+						m.engine.StopEndpoint(id)
+					}
+				}
+				return m, removeEndpointsCmd(m.config, ids)
+			}
+		}
+
 		// Custom Key Handling for View Config
-		if msg, ok := msg.(tea.KeyMsg); ok {
+		if msg, ok := msg.(tea.KeyMsg); ok && !filtering {
 			switch msg.String() {
 			case "tab", "shift+tab":
 				m.activeView++
@@ -382,7 +600,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if editor == "" {
 						editor = "nano"
 					}
-					c := exec.Command(editor, m.selectedFile)
+					c := exec.Command(editor, m.scenarioSource())
 					return m, tea.ExecProcess(c, func(err error) tea.Msg {
 						return editorFinishedMsg{err}
 					})
@@ -390,9 +608,35 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Open logs in editor (Logs focused)
 					return m, openLogsInEditor(m.logContent)
 				}
+			case "t":
+				m.screen = screenSequence
+				return m, nil
+			case "d":
+				if m.activeView == 0 && m.engine != nil {
+					m.screen = screenStats
+					return m, nil
+				}
+			case "E":
+				if m.activeView == 0 {
+					return m, openEditorCmd(m.scenarioSource(), m.scenarioParams)
+				}
+			case "i":
+				if m.activeView == 0 && m.config != nil {
+					if ep, ok := m.selectedEndpoint(); ok {
+						// Messages sent or received by the selected endpoint
+						var indices []int
+						for i, msg := range m.config.Messages {
+							if msg.From == ep.ID || msg.To == ep.ID {
+								indices = append(indices, i)
+							}
+						}
+						m.openInspector(indices, 0)
+					}
+					return m, nil
+				}
 			case "u":
 				if m.activeView == 0 {
-					return m, loadConfigCmd(sourceLua, m.selectedFile, false)
+					return m, reloadCmd(m.scenarioSource(), m.scenarioOptions(m.scenarioParams), false)
 				}
 			case "left", "h":
 				if m.activeView == 0 {
@@ -409,33 +653,49 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.activeEndpointPanel = 1
 					}
 				}
-			case "r":
-				if m.activeView == 0 {
-					if m.engine != nil && m.config != nil && len(m.config.Endpoints) > 0 {
-						var item list.Item
-						if m.activeEndpointPanel == 0 {
-							item = m.serverEndpoints.SelectedItem()
-						} else {
-							item = m.clientEndpoints.SelectedItem()
-						}
-						if ep, ok := item.(endpointItem); ok {
-							m.engine.StartEndpoint(ep.ID)
-							return m, waitForLog(m.engine.Log)
+			case "r", "s", "R":
+				// Act on the marked endpoints, or the selected one
+				if m.activeView == 0 && m.engine != nil {
+					for _, id := range m.targetEndpoints() {
+						switch msg.String() {
+						case "r":
+							m.engine.StartEndpoint(id)
+						case "s":
+							m.engine.StopEndpoint(id)
+						case "R":
+							m.engine.RestartEndpoint(id)
 						}
 					}
 				}
-			case "s":
+			case "S", "C":
+				if m.activeView == 0 && m.engine != nil && m.config != nil {
+					if msg.String() == "S" {
+						m.runKind("server")
+					} else {
+						m.runKind("client")
+					}
+				}
+			case " ":
 				if m.activeView == 0 {
-					if m.engine != nil && m.config != nil && len(m.config.Endpoints) > 0 {
-						var item list.Item
-						if m.activeEndpointPanel == 0 {
-							item = m.serverEndpoints.SelectedItem()
-						} else {
-							item = m.clientEndpoints.SelectedItem()
-						}
-						if ep, ok := item.(endpointItem); ok {
-							m.engine.StopEndpoint(ep.ID)
-						}
+					m.toggleMark()
+					return m, nil
+				}
+			case "a":
+				if m.activeView == 0 {
+					m.markVisible()
+					return m, nil
+				}
+			case "x":
+				if m.activeView == 0 {
+					m.confirmRemove = m.targetEndpoints()
+					return m, nil
+				}
+			case "L":
+				if m.activeView == 0 && m.engine != nil && m.engine.HasLoadProfile() {
+					if m.engine.LoadRunning() {
+						m.engine.StopLoad()
+					} else {
+						m.engine.StartLoad()
 					}
 				}
 			case "g":
@@ -446,6 +706,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.activeView == 1 {
 					m.logViewport.GotoBottom()
 				}
+			case "/":
+				if m.activeView == 1 {
+					return m, m.openLogPrompt(logPromptSearch)
+				}
+			case "f":
+				if m.activeView == 1 {
+					return m, m.openLogPrompt(logPromptFilter)
+				}
+			case "n", "N":
+				if m.activeView == 1 && len(m.logMatches) > 0 {
+					step := 1
+					if msg.String() == "N" {
+						step = len(m.logMatches) - 1
+					}
+					m.logMatch = (m.logMatch + step) % len(m.logMatches)
+					m.refreshLogs()
+					m.showLogMatch()
+				}
+			case "o":
+				if m.activeView == 1 {
+					m.logSelectedOnly = !m.logSelectedOnly
+					m.logViewport.GotoBottom()
+					m.refreshLogs()
+				}
+			case "esc":
+				// Clear the marks, unless the list has a filter to clear
+				if m.activeView == 0 && len(m.marked) > 0 &&
+					m.activeEndpointList().FilterState() == list.Unfiltered {
+					clear(m.marked)
+					return m, nil
+				}
+				// Clear the search, then the filters
+				if m.activeView == 1 {
+					if m.logQuery != "" {
+						m.logQuery = ""
+					} else {
+						m.logFilter, m.logSelectedOnly = logFilter{}, false
+					}
+					m.refreshLogs()
+				}
 			}
 		}
 
@@ -458,6 +758,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.clientEndpoints, cmd = m.clientEndpoints.Update(msg)
 			}
 			cmds = append(cmds, cmd)
+			if m.logSelectedOnly {
+				m.refreshLogs()
+			}
 		} else {
 			// Update Logs Viewport only when focused
 			m.logViewport, cmd = m.logViewport.Update(msg)
@@ -470,63 +773,182 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func loadConfigCmd(source sourceType, path string, saveCopy bool) tea.Cmd {
+// openScenarioCmd prompts for the parameters a Lua scenario declares, then
+// loads it; scenarios without parameters load directly.
+func openScenarioCmd(source sourceType, path string, saveCopy bool, opts lua.Options) tea.Cmd {
+	load := loadConfigCmd(path, saveCopy, opts)
+	if source != sourceLua {
+		return load
+	}
 	return func() tea.Msg {
-		var (
-			cfg *types.Config
-			err error
-		)
-
-		switch source {
-		case sourcePCAP:
-			cfg, err = pcapreader.ReadPCAP(path)
-		case sourceLua:
-			cfg, err = lua.ReadLuaConfig(path)
+		declared, err := lua.DeclaredParams(path, opts)
+		if err != nil {
+			return errMsg{err}
 		}
+		if len(declared) > 0 {
+			return paramsPromptMsg{path: path, saveCopy: saveCopy, params: declared}
+		}
+		return load()
+	}
+}
 
+// luaOptions returns the script options of the app config with params.
+func luaOptions(params map[string]string) lua.Options {
+	appConfig, err := config.LoadDefault()
+	if err != nil {
+		appConfig = config.Default()
+	}
+	opts := lua.OptionsFor(appConfig)
+	opts.Params = params
+	return opts
+}
+
+// scenarioOptions returns the options scenarios are loaded with: those of
+// luaOptions, with the -remap rules tried before the ones of nabu.json.
+func (m Model) scenarioOptions(params map[string]string) lua.Options {
+	opts := luaOptions(params)
+	opts.Remap = slices.Concat(m.remap, opts.Remap)
+	return opts
+}
+
+func loadConfigCmd(path string, saveCopy bool, opts lua.Options) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := lua.ReadScenario(context.Background(), path, opts)
+
+		// Ask again for parameters that are still missing
+		var perr *lua.ParamsError
+		if errors.As(err, &perr) {
+			return paramsPromptMsg{path: path, saveCopy: saveCopy, params: perr.Params, err: perr}
+		}
 		if err != nil {
 			return errMsg{err}
 		}
 
-		// Validate the config
-		if err := lua.ValidateConfig(cfg); err != nil {
-			return errMsg{fmt.Errorf("invalid config: %w", err)}
+		// The stored copy keeps the scenario's original addresses
+		original := *cfg
+		original.Endpoints = slices.Clone(cfg.Endpoints)
+		if err := lua.PrepareScenario(cfg, opts); err != nil {
+			return errMsg{err}
 		}
 
 		finalPath := path
 		if saveCopy {
-			newPath, err := lua.SaveToRecent(cfg, path)
+			newPath, err := lua.SaveToRecent(&original, path)
 			if err != nil {
 				return errMsg{err}
 			}
 			finalPath = newPath
+			// Captures have no source of their own, so the copy is what
+			// u, e and the watcher read from
+			if len(cfg.Sources) == 0 {
+				cfg.Sources = []string{newPath}
+			}
 		}
 
 		return configLoadedMsg{config: cfg, path: finalPath}
 	}
 }
 
+// selectedEndpoint returns the endpoint selected in the focused panel.
+func (m Model) selectedEndpoint() (endpointItem, bool) {
+	var item list.Item
+	if m.activeEndpointPanel == 0 {
+		item = m.serverEndpoints.SelectedItem()
+	} else {
+		item = m.clientEndpoints.SelectedItem()
+	}
+	ep, ok := item.(endpointItem)
+	return ep, ok
+}
+
+// openInspector shows the payload inspector on the given messages.
+func (m *Model) openInspector(indices []int, cursor int) {
+	m.inspector = NewInspector(m.config.Messages, indices, cursor)
+	m.inspector.SetSize(m.width-4, m.height-4)
+	m.inspectorBack = m.screen
+	m.screen = screenInspector
+}
+
+// setEndpointLists fills the server and client panels from m.config,
+// keeping the selected endpoint of each panel when it still exists.
+func (m *Model) setEndpointLists() {
+	var servers, clients []types.Endpoint
+	for _, ep := range m.config.Endpoints {
+		if ep.Kind == "server" {
+			servers = append(servers, ep)
+		} else {
+			clients = append(clients, ep)
+		}
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].ID < servers[j].ID })
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ID < clients[j].ID })
+
+	// Forget marks of endpoints that are gone
+	for id := range m.marked {
+		if !slices.ContainsFunc(m.config.Endpoints, func(ep types.Endpoint) bool { return ep.ID == id }) {
+			delete(m.marked, id)
+		}
+	}
+
+	m.serverEndpoints = newEndpointList(servers, m.serverEndpoints, m.height, m.marked)
+	m.clientEndpoints = newEndpointList(clients, m.clientEndpoints, m.height, m.marked)
+}
+
+func newEndpointList(eps []types.Endpoint, prev list.Model, height int, marked map[int]bool) list.Model {
+	selected := -1
+	if item, ok := prev.SelectedItem().(endpointItem); ok {
+		selected = item.ID
+	}
+
+	items := []list.Item{}
+	for _, ep := range eps {
+		items = append(items, endpointItem(ep))
+	}
+	width, h := 30, (height-7)/2
+	if prev.Width() > 0 {
+		width, h = prev.Width(), prev.Height()
+	}
+	l := list.New(items, endpointsDelegate{marked: marked}, width, h)
+	l.SetShowHelp(false)
+	l.SetShowTitle(false)
+	for i, ep := range eps {
+		if ep.ID == selected {
+			l.Select(i)
+		}
+	}
+	return l
+}
+
 type configLoadedMsg struct {
 	config *types.Config
 	path   string
 }
 
+type paramsPromptMsg struct {
+	path     string
+	saveCopy bool
+	params   []lua.Param
+	err      error
+}
+
 type loadedMsg struct{}
 type errMsg struct{ err error }
 type editorFinishedMsg struct{ err error }
 type logErrorMsg struct{ err error }
-type logMsg string
+type eventMsg struct {
+	sub   *engine.Subscription
+	event engine.Event
+}
 
-func waitForLog(logger *engine.Logger) tea.Cmd {
+func waitForEvent(sub *engine.Subscription) tea.Cmd {
 	return func() tea.Msg {
-		ch := logger.Chan()
-		if ch == nil {
+		if sub == nil {
 			return nil
 		}
-		msg, ok := <-ch
+		ev, ok := <-sub.C
 		if !ok {
 			return nil
 		}
-		return logMsg(msg)
+		return eventMsg{sub: sub, event: ev}
 	}
 }
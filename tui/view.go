@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/samaelod/nabu/lua"
 	"github.com/samaelod/nabu/types"
 )
 
@@ -21,7 +23,9 @@ func (e endpointItem) Title() string {
 func (e endpointItem) Description() string { return "" }
 func (e endpointItem) FilterValue() string { return e.Title() }
 
-type endpointsDelegate struct{}
+type endpointsDelegate struct {
+	marked map[int]bool // Shared with Model.marked
+}
 
 func renderScrollbar(vp viewport.Model, height int) string {
 	total := vp.TotalLineCount()
@@ -71,14 +75,20 @@ func (d endpointsDelegate) Render(w io.Writer, m list.Model, index int, listItem
 	str := fmt.Sprintf("[%d] %s:%d", i.ID, i.Address, i.Port)
 	isSelected := index == m.Index()
 
+	// Marked endpoints get a check, and the others room for one
+	mark := ""
+	if d.marked[i.ID] {
+		mark = lipgloss.NewStyle().Foreground(colorSuccess).Render("✓ ")
+	} else if len(d.marked) > 0 {
+		mark = "  "
+	}
+
 	if isSelected {
 		style := styleSelected.Copy().Foreground(colorSecondary)
-		str = "> " + str
-		fmt.Fprint(w, style.Render(str))
+		fmt.Fprint(w, style.Render("> ")+mark+style.Render(str))
 	} else {
 		style := lipgloss.NewStyle().Foreground(colorText)
-		str = "  " + str
-		fmt.Fprint(w, style.Render(str))
+		fmt.Fprint(w, "  "+mark+style.Render(str))
 	}
 }
 
@@ -113,20 +123,19 @@ func (m Model) View() string {
 		// Custom Card View for Menu
 		menuTitle := styleTitle.Render("Select Source")
 
-		var cardPCAP, cardLua string
-
-		if m.menuCursor == 0 {
-			cardPCAP = styleMenuItemSelected.Render("PCAP File")
-			cardLua = styleMenuItem.Render("Lua Script")
-		} else {
-			cardPCAP = styleMenuItem.Render("PCAP File")
-			cardLua = styleMenuItemSelected.Render("Lua Script")
+		var cards []string
+		for i, label := range []string{"PCAP File", "Lua Script", "Recent"} {
+			if i == m.menuCursor {
+				cards = append(cards, styleMenuItemSelected.Render(label))
+			} else {
+				cards = append(cards, styleMenuItem.Render(label))
+			}
 		}
 
 		menuContent := lipgloss.JoinVertical(lipgloss.Center,
 			menuTitle,
 			"\n",
-			lipgloss.JoinHorizontal(lipgloss.Center, cardPCAP, cardLua),
+			lipgloss.JoinHorizontal(lipgloss.Center, cards...),
 		)
 
 		// Title at top, menu centered in remaining space
@@ -212,9 +221,13 @@ func (m Model) View() string {
 		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
 
 		var status string
-		if m.err != nil {
-			status = styleSubtext.Render("Error: " + m.err.Error())
-		} else {
+		var verr *lua.ValidationError
+		switch {
+		case errors.As(m.err, &verr):
+			status = renderProblems(verr.Problems, windowWidth-8, windowHeight-6)
+		case m.err != nil:
+			status = styleSubtext.Render("Error: "+m.err.Error()) + "\n\n" + styleSubtext.Render("esc back • q quit")
+		default:
 			status = "Loading..."
 		}
 
@@ -228,6 +241,68 @@ func (m Model) View() string {
 			),
 		)
 
+	case screenParams:
+		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
+
+		content = lipgloss.Place(
+			windowWidth, windowHeight,
+			lipgloss.Center, lipgloss.Center,
+			lipgloss.JoinVertical(lipgloss.Center,
+				appTitle,
+				"\n",
+				m.paramForm.View(windowWidth-8),
+			),
+		)
+
+	case screenSequence:
+		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
+		follow := " follow"
+		if m.sequence.Follow {
+			follow = " unfollow"
+		}
+		footer := renderFooter(windowWidth-2,
+			"↑/↓", " scroll", "←/→", " endpoints", "f", follow, "enter", " inspect", "esc", " back", "q", " quit")
+		content = lipgloss.JoinVertical(lipgloss.Top, appTitle, m.sequence.View(), footer)
+
+	case screenStats:
+		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
+		footer := renderFooter(windowWidth-2, "↑/↓", " scroll endpoints", "esc", " back", "q", " quit")
+		content = lipgloss.JoinVertical(lipgloss.Top, appTitle, m.stats.View(), footer)
+
+	case screenRecent:
+		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
+		var footer string
+		if m.recent.Renaming {
+			footer = renderFooter(windowWidth-2, "enter", " rename", "esc", " cancel")
+		} else {
+			footer = renderFooter(windowWidth-2,
+				"↑/↓", " select", "enter", " open", "r", " rename", "d", " delete", "p", " pin", "esc", " back", "q", " quit")
+		}
+		content = lipgloss.JoinVertical(lipgloss.Top, appTitle, m.recent.View(), footer)
+
+	case screenEditor:
+		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
+		var footer string
+		if m.editor.Form != nil {
+			footer = renderFooter(windowWidth-2,
+				"tab", " next field", "ctrl+t", " payload as text/hex", "enter", " apply", "esc", " cancel")
+		} else {
+			footer = renderFooter(windowWidth-2,
+				"tab", " section", "a", " add", "e", " edit", "c", " copy", "d", " delete", "K/J", " move", "w", " save", "esc", " back")
+		}
+		content = lipgloss.JoinVertical(lipgloss.Top, appTitle, m.editor.View(), footer)
+
+	case screenInspector:
+		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
+		var footer string
+		if m.inspector.Searching {
+			footer = renderFooter(windowWidth-2, "enter", " search", "esc", " cancel")
+		} else {
+			footer = renderFooter(windowWidth-2,
+				"↑/↓", " select", "pgup/pgdn", " scroll", "/", " search", "n/N", " match", "esc", " back", "q", " quit")
+		}
+		content = lipgloss.JoinVertical(lipgloss.Top, appTitle, m.inspector.View(), footer)
+
 	case screenViewConfig:
 		// App title
 		appTitle := styleAppTitle.Width(windowWidth).Render("NABU " + m.version)
@@ -368,8 +443,21 @@ func (m Model) View() string {
 			logsColor = colorSecondary
 		}
 
-		// Add margin below title to match Endpoints panel spacing
-		logsTitle := styleTitle.MarginBottom(1).Render("Logs")
+		// The line below the title, blank in the Endpoints panel, holds the
+		// search or filter being typed
+		logsTitle := styleTitle.Render("Logs")
+		if status := m.logsStatus(); status != "" {
+			logsTitle += " " + styleSubtext.Render(truncate(status, max(m.logViewport.Width-6, 0)))
+		}
+		var promptLine string
+		if m.logPrompt != logPromptNone {
+			m.logInput.Width = max(m.logViewport.Width-len(m.logInput.Prompt)-1, 1)
+			promptLine = m.logInput.View()
+		}
+		if m.logErr != nil {
+			promptLine = lipgloss.NewStyle().Foreground(colorError).Render(truncate(m.logErr.Error(), m.logViewport.Width))
+		}
+		logsTitle += "\n" + promptLine
 
 		// Render viewport and scrollbar side by side
 		viewportContent := m.logViewport.View()
@@ -408,34 +496,91 @@ func (m Model) View() string {
 		if m.activeView == 0 {
 			// Endpoints focused: arrows switch servers/clients, e, u, r, s
 			arrowHint := keyStyle.Render("←/→") + descStyle.Render(" switch")
-			footer = lipgloss.JoinHorizontal(lipgloss.Center,
+			hints := []string{
 				tabHint,
 				sep,
 				arrowHint,
 				sep,
-				keyStyle.Render("e"), descStyle.Render(" edit"),
+				keyStyle.Render("e/E"), descStyle.Render(" edit"),
 				sep,
 				keyStyle.Render("u"), descStyle.Render(" update"),
 				sep,
+				keyStyle.Render("t"), descStyle.Render(" sequence"),
+				sep,
+				keyStyle.Render("d"), descStyle.Render(" stats"),
+				sep,
+				keyStyle.Render("i"), descStyle.Render(" inspect"),
+				sep,
 				keyStyle.Render("r"), descStyle.Render(" run"),
 				sep,
 				keyStyle.Render("s"), descStyle.Render(" stop"),
 				sep,
-				keyStyle.Render("q"), descStyle.Render(" quit"),
-			)
+				keyStyle.Render("space/a"), descStyle.Render(" mark"),
+				sep,
+				keyStyle.Render("S/C"), descStyle.Render(" run servers/clients"),
+			}
+			if m.engine != nil && m.engine.HasLoadProfile() {
+				loadDesc := " load"
+				if m.engine.LoadRunning() {
+					loadDesc = " stop load"
+				}
+				hints = append(hints, sep, keyStyle.Render("L"), descStyle.Render(loadDesc))
+			}
+			hints = append(hints, sep, keyStyle.Render("q"), descStyle.Render(" quit"))
+			footer = lipgloss.JoinHorizontal(lipgloss.Center, hints...)
+
+			// Marked endpoints switch the footer to the bulk actions
+			switch {
+			case len(m.confirmRemove) > 0:
+				footer = lipgloss.JoinHorizontal(lipgloss.Center,
+					lipgloss.NewStyle().Foreground(colorError).Render("Remove "+describeEndpoints(m.confirmRemove)+" from this session? "),
+					keyStyle.Render("x"), descStyle.Render(" confirm"),
+					sep,
+					descStyle.Render("any other key cancels"),
+				)
+			case m.bulkErr != nil:
+				footer = lipgloss.NewStyle().Foreground(colorError).Render(m.bulkErr.Error())
+			case len(m.marked) > 0:
+				footer = lipgloss.JoinHorizontal(lipgloss.Center,
+					styleValue.Render(fmt.Sprintf("%d marked", len(m.marked))),
+					sep,
+					keyStyle.Render("space/a"), descStyle.Render(" mark"),
+					sep,
+					keyStyle.Render("r"), descStyle.Render(" run"),
+					sep,
+					keyStyle.Render("s"), descStyle.Render(" stop"),
+					sep,
+					keyStyle.Render("R"), descStyle.Render(" restart"),
+					sep,
+					keyStyle.Render("x"), descStyle.Render(" remove"),
+					sep,
+					keyStyle.Render("esc"), descStyle.Render(" clear"),
+				)
+			}
 		} else {
-			// Logs focused: e (editor), g (top), G (bottom)
+			// Logs focused: e (editor), search and filters
 			footer = lipgloss.JoinHorizontal(lipgloss.Center,
 				tabHint,
 				sep,
 				keyStyle.Render("e"), descStyle.Render(" editor"),
 				sep,
-				keyStyle.Render("g"), descStyle.Render(" top"),
+				keyStyle.Render("/"), descStyle.Render(" search"),
 				sep,
-				keyStyle.Render("G"), descStyle.Render(" bottom"),
+				keyStyle.Render("n/N"), descStyle.Render(" match"),
+				sep,
+				keyStyle.Render("f"), descStyle.Render(" filter"),
+				sep,
+				keyStyle.Render("o"), descStyle.Render(" selected"),
 				sep,
 				keyStyle.Render("q"), descStyle.Render(" quit"),
 			)
+			if m.logPrompt != logPromptNone {
+				footer = lipgloss.JoinHorizontal(lipgloss.Center,
+					keyStyle.Render("enter"), descStyle.Render(" apply"),
+					sep,
+					keyStyle.Render("esc"), descStyle.Render(" cancel"),
+				)
+			}
 		}
 
 		// Wrap footer in a thin border panel
@@ -461,6 +606,28 @@ func (m Model) View() string {
 		Render(content)
 }
 
+// renderFooter draws key hints given as key, description pairs in the
+// bordered footer used below the main panels.
+func renderFooter(width int, pairs ...string) string {
+	keyStyle := lipgloss.NewStyle().Foreground(colorSecondary).Bold(true)
+	descStyle := lipgloss.NewStyle().Foreground(colorSubtext)
+
+	var hints []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			hints = append(hints, descStyle.Render(" • "))
+		}
+		hints = append(hints, keyStyle.Render(pairs[i]), descStyle.Render(pairs[i+1]))
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colorSubtext).
+		Padding(0, 1).
+		Width(width).
+		Render(lipgloss.JoinHorizontal(lipgloss.Center, hints...))
+}
+
 func renderEndpointDetails(m Model, width, height int) string {
 	if m.config == nil || len(m.config.Endpoints) == 0 {
 		return "No endpoint selected"
@@ -557,11 +724,16 @@ func renderEndpointDetails(m Model, width, height int) string {
 			kindStr = fmt.Sprintf("[%s] ", strings.ToUpper(msg.Kind))
 		}
 
+		var size string
+		if n := len(msg.Value) / 2; n > 0 {
+			size = fmt.Sprintf(" %d B", n)
+		}
+
 		var line string
 		if msg.From == ep.ID {
-			line = fmt.Sprintf("→ %sto %d (+%d ms)", kindStr, msg.To, msg.TDelta)
+			line = fmt.Sprintf("→ %sto %d (+%d ms)%s", kindStr, msg.To, msg.TDelta, size)
 		} else if msg.To == ep.ID {
-			line = fmt.Sprintf("← %sfrom %d (+%d ms)", kindStr, msg.From, msg.TDelta)
+			line = fmt.Sprintf("← %sfrom %d (+%d ms)%s", kindStr, msg.From, msg.TDelta, size)
 		}
 
 		if line != "" {
@@ -607,3 +779,25 @@ func renderEndpointDetails(m Model, width, height int) string {
 
 // Additional style needed for subtext which I missed in styles.go
 var styleSubtext = lipgloss.NewStyle().Foreground(colorSubtext)
+
+// renderProblems draws the validation problems of a rejected scenario as a
+// panel of at most height lines.
+func renderProblems(problems []lua.Problem, width, height int) string {
+	title := styleTitle.MarginBottom(1).Render(fmt.Sprintf("Scenario has %d problems", len(problems)))
+
+	maxLines := max(height-6, 1)
+	var lines []string
+	for i, p := range problems {
+		if i == maxLines && len(problems) > maxLines+1 {
+			lines = append(lines, styleSubtext.Render(fmt.Sprintf("... and %d more (run nabu lint)", len(problems)-i)))
+			break
+		}
+		lines = append(lines, lipgloss.NewStyle().Foreground(colorError).Render("• ")+p.String())
+	}
+
+	body := title + "\n" + strings.Join(lines, "\n") + "\n\n" + styleSubtext.Render("esc back • q quit")
+	return stylePanelTitled.
+		BorderForeground(colorError).
+		Width(min(width, 100)).
+		Render(body)
+}
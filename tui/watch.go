@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/samaelod/nabu/lua"
+	"github.com/samaelod/nabu/types"
+)
+
+// watchInterval is how often the files of the loaded scenario are checked.
+// Polling keeps nabu free of platform file-notification code and copes with
+// editors that save by renaming a new file into place.
+const watchInterval = 500 * time.Millisecond
+
+// fileStamp identifies one version of a file; the zero value means missing.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func stampFiles(paths []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, p := range paths {
+		stamps[p] = stampFile(p)
+	}
+	return stamps
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{mod: info.ModTime(), size: info.Size()}
+}
+
+// watchMsg is the result of one check; changed names a file that differs
+// from its stamp, or is empty.
+type watchMsg struct {
+	gen     int
+	changed string
+}
+
+// watchCmd checks stamps after watchInterval. gen ties the result to the
+// scenario being watched, so checks for a replaced scenario are dropped.
+func watchCmd(gen int, stamps map[string]fileStamp) tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		for path, st := range stamps {
+			if stampFile(path) != st {
+				return watchMsg{gen: gen, changed: path}
+			}
+		}
+		return watchMsg{gen: gen}
+	})
+}
+
+// watchScenario starts watching the files the current scenario was read
+// from, replacing any previous watch. Changes reload the first of them, see
+// scenarioSource.
+func (m *Model) watchScenario() tea.Cmd {
+	m.watchGen++
+	m.watchFiles = m.config.Sources
+	if len(m.watchFiles) == 0 {
+		return nil
+	}
+	m.watchStamps = stampFiles(m.watchFiles)
+	return watchCmd(m.watchGen, m.watchStamps)
+}
+
+// scenarioSource is the file the current scenario is reloaded from. Opened
+// scenarios run from their copy in the recent directory, but edits are made
+// to the original and its includes, so those are read again.
+func (m *Model) scenarioSource() string {
+	if m.config != nil && len(m.config.Sources) > 0 {
+		return m.config.Sources[0]
+	}
+	return m.selectedFile
+}
+
+type reloadedMsg struct {
+	config *types.Config
+}
+
+type reloadFailedMsg struct {
+	err  error
+	auto bool // Triggered by the watcher rather than by the user
+}
+
+// reloadCmd reads the scenario at path again for Engine.Reload.
+func reloadCmd(path string, opts lua.Options, auto bool) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := lua.LoadScenario(context.Background(), path, opts)
+
+		var perr *lua.ParamsError
+		if !auto && errors.As(err, &perr) {
+			return paramsPromptMsg{path: path, params: perr.Params, err: perr}
+		}
+		if err != nil {
+			return reloadFailedMsg{err: err, auto: auto}
+		}
+		return reloadedMsg{config: cfg}
+	}
+}
@@ -1,10 +1,15 @@
 package types
 
+// Config is a scenario. The lua tags name the Lua key of every field and,
+// with omitempty, leave zero values out when writing; lua.ReadLuaConfig and
+// lua.WriteConfig both rely on them, so new fields only need a tag to
+// round-trip.
 type Config struct {
-	Globals        Globals
-	Endpoints      []Endpoint
-	Messages       []Message
-	MessagesByFrom map[int][]Message // Pre-indexed messages by sender endpoint ID
+	Globals        Globals           `lua:"globals"`
+	Endpoints      []Endpoint        `lua:"endpoints"`
+	Messages       []Message         `lua:"messages"`
+	MessagesByFrom map[int][]Message `lua:"-"` // Pre-indexed messages by sender endpoint ID
+	Sources        []string          `lua:"-"` // Files read to build a Lua scenario, itself first
 }
 
 // IndexMessages populates MessagesByFrom for O(1) lookup by sender
@@ -16,26 +21,71 @@ func (c *Config) IndexMessages() {
 }
 
 type Globals struct {
-	Protocol string
-	PlayMode string
-	Timeout  int // ms
-	Delay    int // ms
-	LogLines int // Max lines in memory buffer (default 1000)
+	Protocol string `lua:"protocol"`
+	PlayMode string `lua:"play_mode"`
+	Timeout  int    `lua:"timeout"`             // ms, connect timeout
+	Delay    int    `lua:"delay"`               // ms
+	LogLines int    `lua:"log_lines,omitempty"` // Max lines in memory buffer (default 1000)
+
+	ReadTimeout  int `lua:"read_timeout,omitempty"`  // ms without receiving data before a connection fails (0 = off)
+	WriteTimeout int `lua:"write_timeout,omitempty"` // ms allowed per write (0 = use Timeout)
+	IdleTimeout  int `lua:"idle_timeout,omitempty"`  // ms without traffic before a connection is closed (0 = off)
+	FinDrain     int `lua:"fin_drain,omitempty"`     // ms a fin waits for the peer to close before closing fully (0 = don't wait)
+
+	Iterations int `lua:"iterations,omitempty"` // Times each client repeats its trace (0 = once, or unlimited with Duration)
+	Duration   int `lua:"duration,omitempty"`   // ms a client keeps repeating its trace (0 = off)
+	ThinkTime  int `lua:"think_time,omitempty"` // ms pause between iterations
+
+	BindSource bool     `lua:"bind_source,omitempty"` // Bind client sockets to their endpoint address/port
+	SourcePool []string `lua:"source_pool,omitempty"` // Local addresses rotated across client connections
+	Remap      []string `lua:"remap,omitempty"`       // Endpoint remap rules, see package remap
+
+	Load LoadProfile `lua:"load,omitempty"` // Open-model load profile, disabled when it has no stages
+}
+
+// LoadProfile starts new client sessions at a target arrival rate,
+// independent of how long each session takes.
+type LoadProfile struct {
+	Endpoints   []int       `lua:"endpoints,omitempty"`    // Client endpoints used as session templates (default: all clients)
+	StartRate   float64     `lua:"start_rate,omitempty"`   // sessions/s at the beginning of the first stage
+	MaxSessions int         `lua:"max_sessions,omitempty"` // Cap on concurrent sessions, extra arrivals are dropped (0 = unlimited)
+	Stages      []LoadStage `lua:"stages,omitempty"`
+}
+
+type LoadStage struct {
+	Duration int     `lua:"duration"`        // ms
+	Rate     float64 `lua:"rate"`            // Target sessions/s
+	Shape    string  `lua:"shape,omitempty"` // "ramp" (default): interpolate from the previous rate | "step": jump to Rate
 }
 
 type Endpoint struct {
-	ID      int
-	Kind    string // "server" | "client"
-	Address string
-	Port    int
+	ID      int    `lua:"id"`
+	Kind    string `lua:"kind"` // "server" | "client"
+	Address string `lua:"address"`
+	Port    int    `lua:"port"`
+
+	Bind       bool     `lua:"bind,omitempty"`        // Bind to Address/Port when connecting (clients)
+	SourcePool []string `lua:"source_pool,omitempty"` // Overrides Globals.SourcePool for this endpoint
+
+	// Per-endpoint timeout overrides in ms (0 = use Globals)
+	Timeout      int `lua:"timeout,omitempty"`
+	ReadTimeout  int `lua:"read_timeout,omitempty"`
+	WriteTimeout int `lua:"write_timeout,omitempty"`
+	IdleTimeout  int `lua:"idle_timeout,omitempty"`
+	FinDrain     int `lua:"fin_drain,omitempty"`
+
+	// Per-endpoint loop overrides (0 = use Globals)
+	Iterations int `lua:"iterations,omitempty"`
+	Duration   int `lua:"duration,omitempty"`
+	ThinkTime  int `lua:"think_time,omitempty"`
 }
 
 type Message struct {
-	From   int
-	To     int
-	Kind   string // "syn", "ack", "data", etc.
-	Value  string // hex
-	TDelta int    // ms since previous message
+	From   int    `lua:"from"`
+	To     int    `lua:"to"`
+	Kind   string `lua:"kind"`    // "syn", "ack", "data", etc.
+	Value  string `lua:"value"`   // hex
+	TDelta int    `lua:"t_delta"` // ms since previous message
 }
 
 type EndpointStatus int